@@ -0,0 +1,161 @@
+package strata
+
+// AggregateFunction is the enumerated aggregate that is applied to a field
+type AggregateFunction int
+
+const (
+	// NoAggregate leaves the field as is
+	NoAggregate AggregateFunction = iota
+	// Count counts the non-null values of the field
+	Count
+	// CountDistinct counts the distinct non-null values of the field
+	CountDistinct
+	// Sum adds the values of the field together
+	Sum
+	// Avg averages the values of the field
+	Avg
+	// Min returns the smallest value of the field
+	Min
+	// Max returns the largest value of the field
+	Max
+	// StringAgg concatenates the text values of the field using the Delimiter of the field
+	StringAgg
+	// ArrayAgg collects the values of the field into an array
+	ArrayAgg
+	// JSONAgg collects the values of the field into a json array
+	JSONAgg
+	// STUnion merges the geometries of the field into a single geometry
+	STUnion
+	// STCollect collects the geometries of the field into a geometry collection
+	STCollect
+)
+
+// defaultDelimiter is used by StringAgg when the field has no Delimiter
+const defaultDelimiter = ", "
+
+// SQL returns the name of the aggregate function
+func (af *AggregateFunction) SQL() string {
+	if af == nil {
+		return ""
+	}
+	switch *af {
+	case Count, CountDistinct:
+		return "COUNT"
+	case Sum:
+		return "SUM"
+	case Avg:
+		return "AVG"
+	case Min:
+		return "MIN"
+	case Max:
+		return "MAX"
+	case StringAgg:
+		return "STRING_AGG"
+	case ArrayAgg:
+		return "ARRAY_AGG"
+	case JSONAgg:
+		return "JSON_AGG"
+	case STUnion:
+		return "ST_Union"
+	case STCollect:
+		return "ST_Collect"
+	case NoAggregate:
+		fallthrough
+	default:
+		return ""
+	}
+}
+
+// resultType returns the type of the aggregated value given the type of
+// the field that is aggregated
+func (af AggregateFunction) resultType(_type FieldType) FieldType {
+	switch af {
+	case Count, CountDistinct:
+		return Number
+	case StringAgg:
+		return String
+	case ArrayAgg, JSONAgg:
+		return Nil
+	default:
+		return _type
+	}
+}
+
+// aggregateSQL wraps the selector of the field in its aggregate function
+func (tf *TableField) aggregateSQL(selector string) string {
+	fn := tf.Aggregate.SQL()
+	if fn == "" {
+		return selector
+	}
+
+	switch tf.Aggregate {
+	case CountDistinct:
+		selector = "DISTINCT " + selector
+	case StringAgg:
+		selector += "::text"
+		delimiter := tf.Delimiter
+		if delimiter == "" {
+			delimiter = defaultDelimiter
+		}
+		selector += ", " + insertSingleQuotes(delimiter)
+	}
+	return fn + "(" + selector + ")"
+}
+
+// IsAggregate returns whether an aggregate function is applied to the field,
+// other than over a window
+func (tf *TableField) IsAggregate() bool {
+	return tf.Aggregate != NoAggregate && !tf.IsWindow()
+}
+
+// AggregateField returns a copy of the field with the aggregate function
+// applied to it
+func AggregateField(tf TableField, aggregate AggregateFunction) TableField {
+	tf.Aggregate = aggregate
+	tf.Type = aggregate.resultType(tf.Type)
+	return tf
+}
+
+// CountAllField returns a COUNT(*) field
+func CountAllField(friendlyName string) TableField {
+	return TableField{
+		FormattedName: "*",
+		FriendlyName:  friendlyName,
+		Aggregate:     Count,
+		Type:          Number,
+	}
+}
+
+func (tf *TableFields) addAggregateField(alias *string, name, friendlyName string, aggregate AggregateFunction) {
+	_type := Nil
+	if field := tf.fieldByName(name); field != nil {
+		_type = field.Type
+	}
+	field := AggregateField(makeField(alias, name, friendlyName, "", _type), aggregate)
+	tf.append(field)
+}
+
+// groupingFields returns the fields that are not aggregated (or computed over
+// a window) if any of the fields are aggregated, as they would need to be
+// grouped by
+func groupingFields(fields []*TableField) []*TableField {
+	var (
+		grouping   []*TableField
+		aggregated bool
+	)
+	for _, field := range fields {
+		if field.IsAggregate() {
+			aggregated = true
+			continue
+		}
+		if field.IsWindow() {
+			continue
+		}
+		grouping = append(grouping, field)
+	}
+
+	if !aggregated {
+		return nil
+	}
+	return grouping
+}
@@ -0,0 +1,55 @@
+package strata
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAggregatedFieldsAreNotComparedInTheWhereClause(t *testing.T) {
+	ts := &Table{Name: "erf"}
+	ts.AddFields(NumberField("township_id"), AggregateField(NumberField("area"), Sum))
+	q := &Query{}
+	q.SetBaseTable(ts)
+	*ts.Alias = "e"
+	area := ts.FieldByName("area")
+
+	ts.WhereConditions = *area.Where(GreaterThan, 100)
+	if got, err := q.SQL(); err == nil {
+		t.Errorf("got %v, want an error for an aggregate in the WHERE clause", got)
+	}
+
+	ts.WhereConditions = Wheres{}
+	q.SetHaving(area.Where(GreaterThan, 100))
+	got, err := q.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `GROUP BY "e"."township_id" HAVING SUM("e"."area") > 100`
+	if !strings.Contains(got, want) {
+		t.Errorf("got %v, want it to contain %v", got, want)
+	}
+}
+
+func TestBestEffortSQLHasCheckedVariants(t *testing.T) {
+	derived := &Table{Name: "subquery", Subquery: &Query{}}
+	if _, err := derived.SourceSQL(); err == nil {
+		t.Errorf("expected an error for a derived table without a base table")
+	}
+	if got := derived.SQL(); got != "" {
+		t.Errorf("got %v, want an empty string", got)
+	}
+
+	ts := &Table{Name: "erf"}
+	ts.AddFields(NumberField("area"), ExpressionField("class", String, Case()))
+	q := &Query{}
+	q.SetBaseTable(ts)
+	if _, err := q.NestedFieldsSQL(); err == nil {
+		t.Errorf("expected an error for a CASE expression without branches")
+	}
+	if got := q.NestedFields(); got != "" {
+		t.Errorf("got %v, want an empty string", got)
+	}
+	if _, err := ts.FieldByName("class").SelectorSQL(); err == nil {
+		t.Errorf("expected an error for a CASE expression without branches")
+	}
+}
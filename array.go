@@ -0,0 +1,28 @@
+package strata
+
+// MakeUnnestTable returns a table of the elements of the array field, with a
+// single field of the given name. It is evaluated laterally, and can
+// therefore be joined to the table of the array field
+func MakeUnnestTable(array *TableField, name string) *Table {
+	return &Table{
+		Name:     name,
+		Fields:   TableFields{field(name, ParseFieldType(array.ElementType))},
+		Function: functionCall{name: "unnest", args: []interface{}{array}},
+	}
+}
+
+// MakeUnnestJoinTable returns a join table of the elements of the array
+// field, with a single field of the given name. Every row of the table of
+// the array field is joined to each of its elements, which is why the ON
+// clause is TRUE unless another condition is set (or the join is a CROSS
+// join, which has none)
+func MakeUnnestJoinTable(array *TableField, name string, _type JoinType) *JoinTable {
+	jt := &JoinTable{
+		Table:    *MakeUnnestTable(array, name),
+		JoinType: _type,
+	}
+	if _type != CrossJoin {
+		jt.On = Raw("TRUE")
+	}
+	return jt
+}
@@ -0,0 +1,220 @@
+package strata
+
+import "strconv"
+
+// builder carries the state that is shared by every element of a single
+// statement while it is being rendered. When parameterized is set, values
+// are written as Postgres positional placeholders ($1..$n) and collected
+// in args, otherwise they are written inline as literals. aliases holds the
+// aliases of the tables of the enclosing statements, which nested statements
+// may not reuse, scope holds the aliases that the tables in scope are
+// rendered with (innermost last) and selectors holds the rendered selectors
+// of the fields by the alias that they were rendered with. named is set
+// while rendering a statement of which the output columns are referred to
+// by name, such as the query of a derived table, and rows is set while
+// rendering a WHERE clause, which filters the rows before they are grouped
+type builder struct {
+	parameterized bool
+	args          []interface{}
+	aliases       map[string]bool
+	scope         map[*tableRef][]string
+	selectors     map[selectorKey]string
+	named         bool
+	rows          bool
+}
+
+// selectorKey identifies the selector of a field as rendered with an alias
+type selectorKey struct {
+	field *TableField
+	alias string
+}
+
+// Raw is an SQL fragment, such as an expression or a function call, that is
+// written to the statement as is wherever a value is accepted. It is never
+// escaped, and should therefore not contain user input
+type Raw string
+
+// renderer is implemented by the elements that render a complete statement
+type renderer interface {
+	sql(b *builder) (string, error)
+}
+
+// sqlWithArgs renders the element with every value replaced by a
+// positional placeholder, returning the values in placeholder order
+func sqlWithArgs(r renderer) (string, []interface{}, error) {
+	b := parameterizedBuilder()
+	sql, err := r.sql(b)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, b.args, nil
+}
+
+func inlineBuilder() *builder {
+	return &builder{}
+}
+
+func parameterizedBuilder() *builder {
+	return &builder{parameterized: true}
+}
+
+// bind renders the value as the next placeholder of the statement, or as
+// an escaped literal if the builder is not parameterized
+func (b *builder) bind(value interface{}) (string, error) {
+	enc, err := encodeValue(value)
+	if err != nil {
+		return "", err
+	}
+	if b == nil || !b.parameterized {
+		return enc.literal, nil
+	}
+	return b.placeholder(enc.arg, enc.cast), nil
+}
+
+// placeholder collects the argument and returns its placeholder, cast to
+// the given type if any
+func (b *builder) placeholder(arg interface{}, cast string) string {
+	b.args = append(b.args, arg)
+	placeholder := "$" + strconv.Itoa(len(b.args))
+	if cast != "" {
+		placeholder += "::" + cast
+	}
+	return placeholder
+}
+
+// enterScope brings the tables into scope, rendering any table whose alias
+// is already in scope with a new random alias instead, so that a nested
+// statement can not shadow the tables of the enclosing statements. The
+// tables themselves are left as is. The returned function takes the tables
+// out of scope again
+func (b *builder) enterScope(tables ...*Table) func() {
+	if b.aliases == nil {
+		b.aliases = map[string]bool{}
+	}
+	if b.scope == nil {
+		b.scope = map[*tableRef][]string{}
+	}
+
+	entered := []*tableRef{}
+	for _, table := range tables {
+		if table == nil || table.Alias == nil || *table.Alias == "" {
+			continue
+		}
+		alias := *table.Alias
+		for b.aliases[alias] {
+			alias = randomString(4)
+		}
+		ref := table.identity()
+		b.aliases[alias] = true
+		b.scope[ref] = append(b.scope[ref], alias)
+		entered = append(entered, ref)
+	}
+
+	return func() {
+		for _, ref := range entered {
+			aliases := b.scope[ref]
+			delete(b.aliases, aliases[len(aliases)-1])
+			b.scope[ref] = aliases[:len(aliases)-1]
+		}
+	}
+}
+
+// nameOutputs names the output columns of the statements that are rendered
+// until the returned function is called by the output names of the fields,
+// rather than leaving Postgres to name computed columns
+func (b *builder) nameOutputs() func() {
+	named := b.named
+	b.named = true
+	return func() {
+		b.named = named
+	}
+}
+
+// filterRows sets whether the conditions that are rendered until the returned
+// function is called filter rows (i.e. in a WHERE clause), in which case they
+// can not refer to aggregated or windowed fields
+func (b *builder) filterRows(rows bool) func() {
+	previous := b.rows
+	b.rows = rows
+	return func() {
+		b.rows = previous
+	}
+}
+
+// scopedAlias returns the alias that the table with the given identity is
+// rendered with in the innermost scope that it is in
+func (b *builder) scopedAlias(ref *tableRef) (string, bool) {
+	if b == nil || ref == nil || len(b.scope[ref]) == 0 {
+		return "", false
+	}
+	aliases := b.scope[ref]
+	return aliases[len(aliases)-1], true
+}
+
+// tableAlias returns the alias that the table is rendered with, which is its
+// own alias unless that was already in scope
+func (b *builder) tableAlias(t *Table) string {
+	if alias, ok := b.scopedAlias(t.ref); ok {
+		return alias
+	}
+	if t.Alias != nil {
+		return *t.Alias
+	}
+	return ""
+}
+
+// fieldAlias returns the alias that the field is qualified with, which is
+// the alias of the table that it belongs to when that table is in scope
+func (b *builder) fieldAlias(tf *TableField) string {
+	if alias, ok := b.scopedAlias(tf.table); ok {
+		return alias
+	}
+	if tf.Alias != nil {
+		return *tf.Alias
+	}
+	return ""
+}
+
+// operandSQL returns the SQL of a value that is used as an operand, which
+// can either be a reference to another field, a raw SQL fragment or a value
+// to be bound
+func (b *builder) operandSQL(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case *TableField:
+		return v.selectorSQL(b)
+	case Raw:
+		return string(v), nil
+	case *Query:
+		return v.subquerySQL(b)
+	case jsonPath:
+		return b.castOperandSQL(string(v), "jsonpath")
+	case Expression:
+		return v.expressionSQL(b)
+	default:
+		return b.bind(value)
+	}
+}
+
+// castOperandSQL returns the SQL of an operand like operandSQL, with values
+// that are written as strings (including arrays) cast to the given type
+// instead, e.g. 'a.b'::ltree. Fields, raw SQL fragments and subqueries are
+// never cast
+func (b *builder) castOperandSQL(value interface{}, cast string) (string, error) {
+	switch value.(type) {
+	case nil, *TableField, Raw, *Query, Expression:
+		return b.operandSQL(value)
+	}
+
+	enc, err := encodeValue(value)
+	if err != nil {
+		return "", err
+	}
+	text, ok := enc.arg.(string)
+	if !ok {
+		return b.bind(value)
+	}
+	if b == nil || !b.parameterized {
+		return insertSingleQuotes(text) + "::" + cast, nil
+	}
+	return b.placeholder(text, cast), nil
+}
@@ -16,15 +16,105 @@ const (
 	IsNotNull
 	// IsNull is a comparison that is null
 	IsNull
-	// LTreeSubsists prints out the SQL for the operator
+	// LTreeSubsists compares whether the LHS ltree is an ancestor of (or
+	// equal to) the RHS
 	LTreeSubsists
 	// NotILike definition
 	NotILike
 	//NotLike definition
 	NotLike
-	// Locate definition
+	// Locate compares whether the RHS is found within the LHS, optionally
+	// ignoring case or at given positions - see Substring. It is rendered as
+	// a position() predicate rather than as an operator
 	Locate
-	// Remember to add changes to function GetComparisonOperator()
+	// In compares the LHS to a list of values, or to the values returned by a subquery
+	In
+	// NotIn compares the LHS to none of a list of values, or of the values returned by a subquery
+	NotIn
+	// EqualAny compares the LHS to any of the elements of an array, or of
+	// the values returned by a subquery
+	EqualAny
+	// LessThan comparisons will insert < comparisons
+	LessThan
+	// LessThanOrEqual comparisons will insert <= comparisons
+	LessThanOrEqual
+	// GreaterThan comparisons will insert > comparisons
+	GreaterThan
+	// GreaterThanOrEqual comparisons will insert >= comparisons
+	GreaterThanOrEqual
+	// Between compares the LHS to an inclusive range of two values
+	Between
+	// NotBetween compares the LHS to the outside of an inclusive range of two values
+	NotBetween
+	// IsDistinctFrom is an inequality comparison that treats nulls as ordinary values
+	IsDistinctFrom
+	// IsNotDistinctFrom is an equality comparison that treats nulls as ordinary values
+	IsNotDistinctFrom
+	// Matches compares the LHS to a POSIX regular expression
+	Matches
+	// IMatches compares the LHS to a POSIX regular expression, ignoring case
+	IMatches
+	// NotMatches compares the LHS to a POSIX regular expression that it should not match
+	NotMatches
+	// NotIMatches compares the LHS to a POSIX regular expression that it should not match, ignoring case
+	NotIMatches
+	// LTreeDescendant compares whether the LHS ltree is a descendant of (or
+	// equal to) the RHS
+	LTreeDescendant
+	// LTreeMatches compares the LHS ltree to an lquery
+	LTreeMatches
+	// LTreeMatchesText compares the LHS ltree to an ltxtquery
+	LTreeMatchesText
+	// LTreeMatchesAny compares the LHS ltree to any of an array of lqueries
+	LTreeMatchesAny
+	// STIntersects compares whether the LHS geometry intersects the RHS
+	STIntersects
+	// STContains compares whether the LHS geometry contains the RHS
+	STContains
+	// STWithin compares whether the LHS geometry is within the RHS
+	STWithin
+	// STDWithin compares whether the LHS geometry is within a distance of
+	// the RHS - see WithinDistance
+	STDWithin
+	// STTouches compares whether the LHS geometry touches the RHS
+	STTouches
+	// BBoxIntersects compares whether the bounding box of the LHS geometry
+	// intersects the bounding box of the RHS
+	BBoxIntersects
+	// TextSearchMatches compares the LHS tsvector to a full-text search -
+	// see TextSearch
+	TextSearchMatches
+	// JSONContains compares whether the LHS JSON document contains the RHS
+	JSONContains
+	// JSONContainedBy compares whether the LHS JSON document is contained by
+	// the RHS
+	JSONContainedBy
+	// JSONHasKey compares whether the LHS JSON object has the RHS key
+	JSONHasKey
+	// JSONHasAnyKey compares whether the LHS JSON object has any of the RHS
+	// keys
+	JSONHasAnyKey
+	// JSONHasAllKeys compares whether the LHS JSON object has all of the RHS
+	// keys
+	JSONHasAllKeys
+	// JSONPathExists compares whether the RHS path query returns any item
+	// for the LHS JSON document
+	JSONPathExists
+	// JSONPathMatches compares whether the RHS path predicate holds for the
+	// LHS JSON document
+	JSONPathMatches
+	// NotEqualAll compares the LHS to none of the elements of an array, or of
+	// the values returned by a subquery
+	NotEqualAll
+	// ArrayContains compares whether the LHS array contains all of the
+	// elements of the RHS array
+	ArrayContains
+	// ArrayContainedBy compares whether all of the elements of the LHS array
+	// are in the RHS array
+	ArrayContainedBy
+	// ArrayOverlaps compares whether the LHS and RHS arrays have any elements
+	// in common
+	ArrayOverlaps
 )
 
 // IsExact refers to whether or not the comparison type is looking
@@ -34,12 +124,13 @@ func (t ComparisonType) IsExact() bool {
 }
 
 // IsLocate returns whether or not the comparison type should be written
-// as a Locate functionality
+// as a position() predicate rather than as an operator
 func (t ComparisonType) IsLocate() bool {
 	return t == Locate
 }
 
-// SQL operator returns the string representation of the equality type
+// SQL operator returns the string representation of the equality type. It
+// is empty for Locate, which has no operator form
 func (t *ComparisonType) SQL() string {
 	if t == nil {
 		return "IS NOT NULL"
@@ -56,13 +147,89 @@ func (t *ComparisonType) SQL() string {
 	case LTreeSubsists:
 		return "@>"
 	case Equal:
-		return ""
+		return "="
 	case NotLike:
 		return "NOT LIKE"
 	case NotILike:
 		return "NOT ILIKE"
 	case Locate:
-		return "LOCATE"
+		// Locate has no operator form, it is written as a predicate of
+		// its own - see Where.locateSQL
+		return ""
+	case In:
+		return "IN"
+	case NotIn:
+		return "NOT IN"
+	case EqualAny:
+		return "= ANY"
+	case LessThan:
+		return "<"
+	case LessThanOrEqual:
+		return "<="
+	case GreaterThan:
+		return ">"
+	case GreaterThanOrEqual:
+		return ">="
+	case Between:
+		return "BETWEEN"
+	case NotBetween:
+		return "NOT BETWEEN"
+	case IsDistinctFrom:
+		return "IS DISTINCT FROM"
+	case IsNotDistinctFrom:
+		return "IS NOT DISTINCT FROM"
+	case Matches:
+		return "~"
+	case IMatches:
+		return "~*"
+	case NotMatches:
+		return "!~"
+	case NotIMatches:
+		return "!~*"
+	case LTreeDescendant:
+		return "<@"
+	case LTreeMatches:
+		return "~"
+	case LTreeMatchesText:
+		return "@"
+	case LTreeMatchesAny:
+		return "?"
+	case STIntersects:
+		return "ST_Intersects"
+	case STContains:
+		return "ST_Contains"
+	case STWithin:
+		return "ST_Within"
+	case STDWithin:
+		return "ST_DWithin"
+	case STTouches:
+		return "ST_Touches"
+	case BBoxIntersects:
+		return "&&"
+	case TextSearchMatches:
+		return "@@"
+	case JSONContains:
+		return "@>"
+	case JSONContainedBy:
+		return "<@"
+	case JSONHasKey:
+		return "?"
+	case JSONHasAnyKey:
+		return "?|"
+	case JSONHasAllKeys:
+		return "?&"
+	case JSONPathExists:
+		return "@?"
+	case JSONPathMatches:
+		return "@@"
+	case NotEqualAll:
+		return "<> ALL"
+	case ArrayContains:
+		return "@>"
+	case ArrayContainedBy:
+		return "<@"
+	case ArrayOverlaps:
+		return "&&"
 	case IsNotNull:
 		fallthrough
 	default:
@@ -70,15 +237,98 @@ func (t *ComparisonType) SQL() string {
 	}
 }
 
+// IsSpatial returns whether or not the comparison type compares geometries,
+// in which case the RHS is read as a geometry - see Geometry
+func (t ComparisonType) IsSpatial() bool {
+	switch t {
+	case STIntersects, STContains, STWithin, STDWithin, STTouches, BBoxIntersects:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsQuantified returns whether or not the comparison type compares the LHS
+// to the elements of an array (or of a subquery) using ANY or ALL
+func (t ComparisonType) IsQuantified() bool {
+	return t == EqualAny || t == NotEqualAll
+}
+
+// operandArity is the number of values that the RHS of a comparison is made
+// up of
+type operandArity int
+
+const (
+	noOperand operandArity = iota
+	singleOperand
+	rangeOperand
+	listOperand
+)
+
+func (t ComparisonType) arity() operandArity {
+	switch t {
+	case IsNull, IsNotNull:
+		return noOperand
+	case Between, NotBetween:
+		return rangeOperand
+	case In, NotIn:
+		return listOperand
+	default:
+		return singleOperand
+	}
+}
+
 // NeedsRHS indicates whether a RHS field is needed to make a valid comparison
 func (t *ComparisonType) NeedsRHS() bool {
 	if t == nil {
 		return false
 	}
+	return t.arity() != noOperand
+}
 
-	if t == nil {
-		return true
-	}
+// NeedsRange indicates whether the RHS of the comparison is a range of two
+// values, given as a Range or as a slice of two values
+func (t *ComparisonType) NeedsRange() bool {
+	return t != nil && t.arity() == rangeOperand
+}
+
+// NeedsList indicates whether the RHS of the comparison is a list of values,
+// given as a slice or as a subquery
+func (t *ComparisonType) NeedsList() bool {
+	return t != nil && t.arity() == listOperand
+}
 
-	return (*t) != IsNotNull || *t != IsNull
+// valueCast returns the type that values on the RHS of the comparison are
+// cast to, so that strings are read as ltree paths or queries and slices as
+// arrays of the element type of the LHS. Comparisons of ltree fields that are
+// not specific to ltree (such as Equal) compare to ltree paths, or to arrays
+// of them when quantified, except for the pattern matching comparisons
+func (t ComparisonType) valueCast(lhs *TableField) string {
+	switch t {
+	case LTreeSubsists, LTreeDescendant:
+		return "ltree"
+	case LTreeMatches:
+		return "lquery"
+	case LTreeMatchesText:
+		return "ltxtquery"
+	case LTreeMatchesAny:
+		return "lquery[]"
+	case ArrayContains, ArrayContainedBy, ArrayOverlaps:
+		if lhs.ElementType != "" {
+			return lhs.ElementType + "[]"
+		}
+		return ""
+	case EqualAny, NotEqualAll:
+		// The elements are compared to the LHS, so the array is of its type
+		if lhs.Type == LTree {
+			return "ltree[]"
+		}
+		return ""
+	case Like, ILike, NotLike, NotILike, Matches, IMatches, NotMatches, NotIMatches, Locate:
+		return ""
+	}
+	if lhs.Type == LTree {
+		return "ltree"
+	}
+	return ""
 }
@@ -0,0 +1,170 @@
+package strata
+
+import "fmt"
+
+// Condition is a node of a boolean expression tree. Where, Wheres and
+// WhereSet are the leaves of the tree, and And, Or and Not group them
+// together so that they can be nested arbitrarily
+type Condition interface {
+	conditionSQL(b *builder) (string, error)
+}
+
+// operatorCondition is implemented by conditions that can render as several
+// terms joined by a logical operator. The operator is returned along with the
+// SQL, and is empty when the condition rendered as a single term, so that
+// the group that the condition is nested in can tell whether it needs
+// parentheses
+type operatorCondition interface {
+	operatorSQL(b *builder) (string, string, error)
+}
+
+// ConditionGroup is a set of conditions joined together by a single
+// logical operator - AND when inclusive and OR otherwise
+type ConditionGroup struct {
+	Conditions  []Condition
+	IsInclusive bool
+}
+
+// Negation negates the wrapped condition
+type Negation struct {
+	Condition Condition
+}
+
+// And returns a group of conditions that must all hold
+func And(conditions ...Condition) *ConditionGroup {
+	return &ConditionGroup{Conditions: conditions, IsInclusive: true}
+}
+
+// Or returns a group of conditions of which at least one must hold
+func Or(conditions ...Condition) *ConditionGroup {
+	return &ConditionGroup{Conditions: conditions}
+}
+
+// Not returns the negation of the given condition
+func Not(condition Condition) *Negation {
+	return &Negation{Condition: condition}
+}
+
+// Append appends conditions to the group
+func (cg *ConditionGroup) Append(conditions ...Condition) {
+	cg.Conditions = append(cg.Conditions, conditions...)
+}
+
+// SQL returns the SQL representation of the condition group
+func (cg *ConditionGroup) SQL() (string, error) {
+	return cg.conditionSQL(inlineBuilder())
+}
+
+// SQLWithArgs returns the parameterized SQL representation of the condition
+// group, along with the values in placeholder order
+func (cg *ConditionGroup) SQLWithArgs() (string, []interface{}, error) {
+	return conditionSQLWithArgs(cg)
+}
+
+func (cg *ConditionGroup) unionSQL() string {
+	if cg.IsInclusive {
+		return "AND"
+	}
+	return "OR"
+}
+
+func (cg *ConditionGroup) conditionSQL(b *builder) (string, error) {
+	if cg == nil {
+		return "", nil
+	}
+	return groupSQL(b, cg.unionSQL(), cg.Conditions...)
+}
+
+func (cg *ConditionGroup) operatorSQL(b *builder) (string, string, error) {
+	if cg == nil {
+		return "", "", nil
+	}
+	return groupTermsSQL(b, cg.unionSQL(), cg.Conditions...)
+}
+
+// SQL returns the SQL representation of the negated condition
+func (n *Negation) SQL() (string, error) {
+	return n.conditionSQL(inlineBuilder())
+}
+
+// SQLWithArgs returns the parameterized SQL representation of the negated
+// condition, along with the values in placeholder order
+func (n *Negation) SQLWithArgs() (string, []interface{}, error) {
+	return conditionSQLWithArgs(n)
+}
+
+// conditionSQL writes the raw SQL fragment as a condition, i.e. TRUE
+func (r Raw) conditionSQL(b *builder) (string, error) {
+	return string(r), nil
+}
+
+func (n *Negation) conditionSQL(b *builder) (string, error) {
+	if n == nil || n.Condition == nil {
+		return "", nil
+	}
+	sql, err := n.Condition.conditionSQL(b)
+	if err != nil || sql == "" {
+		return "", err
+	}
+	return "NOT (" + sql + ")", nil
+}
+
+// groupSQL joins the non-empty conditions with the given operator, wrapping
+// the conditions that are joined by another operator in parentheses so that
+// precedence is preserved
+func groupSQL(b *builder, operator string, conditions ...Condition) (string, error) {
+	sql, _, err := groupTermsSQL(b, operator, conditions...)
+	return sql, err
+}
+
+// groupTermsSQL joins the non-empty conditions with the given operator, and
+// returns the operator that the result is joined by - which is that of the
+// only condition when there is just one, and empty when that is a single
+// term
+func groupTermsSQL(b *builder, operator string, conditions ...Condition) (string, string, error) {
+	var parts, operators []string
+	for i, condition := range conditions {
+		if condition == nil {
+			continue
+		}
+		var (
+			sql, op string
+			err     error
+		)
+		if c, ok := condition.(operatorCondition); ok {
+			sql, op, err = c.operatorSQL(b)
+		} else {
+			sql, err = condition.conditionSQL(b)
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("Condition %v: %v", i, err)
+		}
+		if sql == "" {
+			continue
+		}
+		parts = append(parts, sql)
+		operators = append(operators, op)
+	}
+
+	switch len(parts) {
+	case 0:
+		return "", "", nil
+	case 1:
+		return parts[0], operators[0], nil
+	}
+	for i := range parts {
+		if operators[i] != "" && operators[i] != operator {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return delimit(surroundWithSpaces(operator), parts...), operator, nil
+}
+
+func conditionSQLWithArgs(c Condition) (string, []interface{}, error) {
+	b := parameterizedBuilder()
+	sql, err := c.conditionSQL(b)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, b.args, nil
+}
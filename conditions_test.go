@@ -0,0 +1,93 @@
+package strata
+
+import "testing"
+
+func TestConditionGroupPrecedence(t *testing.T) {
+	ts := &Table{Name: "township", Alias: new(string)}
+	*ts.Alias = "t"
+	ts.AddFields(NumberField("_id"), StringField("name"))
+	id, name := ts.FieldByName("_id"), ts.FieldByName("name")
+
+	either := Wheres{Wheres: []Where{
+		{LHSField: name, RHSField: "north", ComparisonType: Equal},
+		{LHSField: name, RHSField: "south", ComparisonType: Equal},
+	}}
+	single := Where{LHSField: id, RHSField: 1, ComparisonType: Equal}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		want      string
+	}{
+		{
+			name:      "Or of OR'd Wheres inside And",
+			condition: And(Or(either), single),
+			want:      `("t"."name" = 'north' OR "t"."name" = 'south') AND "t"."_id" = 1`,
+		},
+		{
+			name:      "nested single child groups",
+			condition: And(Or(Or(either)), single),
+			want:      `("t"."name" = 'north' OR "t"."name" = 'south') AND "t"."_id" = 1`,
+		},
+		{
+			name:      "single child group with empty siblings",
+			condition: And(Or(either, Wheres{}, nil), single),
+			want:      `("t"."name" = 'north' OR "t"."name" = 'south') AND "t"."_id" = 1`,
+		},
+		{
+			name:      "single child group of a single term",
+			condition: And(Or(Or(single)), either),
+			want:      `"t"."_id" = 1 AND ("t"."name" = 'north' OR "t"."name" = 'south')`,
+		},
+		{
+			name:      "groups of the same operator",
+			condition: Or(either, Or(single, Raw("FALSE"))),
+			want:      `"t"."name" = 'north' OR "t"."name" = 'south' OR "t"."_id" = 1 OR FALSE`,
+		},
+		{
+			name:      "empty groups and negations",
+			condition: And(And(), Or(Wheres{}), Not(either), single),
+			want:      `NOT ("t"."name" = 'north' OR "t"."name" = 'south') AND "t"."_id" = 1`,
+		},
+		{
+			name:      "WhereSet of a single OR'd Wheres inside And",
+			condition: And(WhereSet{either, Wheres{}}, single),
+			want:      `("t"."name" = 'north' OR "t"."name" = 'south') AND "t"."_id" = 1`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.condition.conditionSQL(inlineBuilder())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableConditionsAreJoinedToWhereConditions(t *testing.T) {
+	ts := &Table{Name: "township", Schema: "cadastral"}
+	ts.AddFields(NumberField("_id"), StringField("name"))
+	q := &Query{}
+	q.SetBaseTable(ts)
+	*ts.Alias = "t"
+
+	name := ts.FieldByName("name")
+	ts.WhereConditions = Wheres{Wheres: []Where{
+		{LHSField: name, RHSField: "north", ComparisonType: Equal},
+		{LHSField: name, RHSField: "south", ComparisonType: Equal},
+	}}
+	ts.Conditions = Not(ts.FieldByName("_id").Where(Equal, 1))
+
+	got, err := q.NestedWheres()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `("t"."name" = 'north' OR "t"."name" = 'south') AND NOT ("t"."_id" = 1)`
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+package strata
+
+import "fmt"
+
+// Materialization is the hint given to Postgres on whether a common table
+// expression is computed once, or folded into the query that refers to it
+type Materialization int
+
+const (
+	// MaterializedDefault leaves the choice to Postgres
+	MaterializedDefault Materialization = iota
+	// Materialized computes the common table expression once
+	Materialized
+	// NotMaterialized allows the common table expression to be folded into
+	// the query that refers to it
+	NotMaterialized
+)
+
+// SQL returns the SQL representation of the materialization hint
+func (m *Materialization) SQL() string {
+	if m == nil {
+		return ""
+	}
+	switch *m {
+	case Materialized:
+		return "MATERIALIZED"
+	case NotMaterialized:
+		return "NOT MATERIALIZED"
+	default:
+		return ""
+	}
+}
+
+// CommonTableExpression is a named statement in the WITH clause of a query,
+// which can be referred to as a table by that query using Table or
+// JoinTable. The statement is either a Query, or a Union - which is the
+// case for recursive common table expressions, where the first query is the
+// non-recursive term and the second refers to the common table expression
+// itself. Columns optionally names the output columns of the statement
+type CommonTableExpression struct {
+	Name         string
+	Columns      []string
+	Query        *Query
+	Union        *Union
+	Materialized Materialization
+	Recursive    bool
+}
+
+// MakeCTE returns a common table expression with the given name for the query
+func MakeCTE(name string, q *Query) *CommonTableExpression {
+	return &CommonTableExpression{Name: name, Query: q}
+}
+
+// MakeRecursiveCTE returns a recursive common table expression with the given
+// name and output columns. As the recursive term refers to the common table
+// expression itself, the Union is set once it has been created, i.e.
+//
+//	cte := strata.MakeRecursiveCTE("region_tree", "id", "path")
+//	tree := cte.ReferenceJoinTable(strata.InnerJoin)
+//	tree.SetLHSField("id").SetEqualTo(regions.FieldByName("parent_id"))
+//	...
+//	cte.Union = strata.MakeUnion(anchor, recursive)
+func MakeRecursiveCTE(name string, columns ...string) *CommonTableExpression {
+	return &CommonTableExpression{Name: name, Columns: columns, Recursive: true}
+}
+
+// outputFields returns the fields that the statement of the common table
+// expression returns, named by their output column names
+func (cte *CommonTableExpression) outputFields() TableFields {
+	fields := TableFields{}
+	if len(cte.Columns) > 0 {
+		for _, column := range cte.Columns {
+			fields.addField(nil, column, "", "", Nil)
+		}
+		return fields
+	}
+
+	var q *Query
+	switch {
+	case cte.Query != nil:
+		q = cte.Query
+	case cte.Union != nil && len(*cte.Union) > 0:
+		q = &(*cte.Union)[0]
+	default:
+		return fields
+	}
+	return q.outputFields()
+}
+
+// Table returns a table that refers to the common table expression, with a
+// field for each of its output columns
+func (cte *CommonTableExpression) Table() *Table {
+	return &Table{
+		Name:   cte.Name,
+		Fields: cte.outputFields(),
+	}
+}
+
+// JoinTable returns a join table that refers to the common table expression,
+// with a field for each of its output columns
+func (cte *CommonTableExpression) JoinTable(_type JoinType) *JoinTable {
+	return &JoinTable{
+		Table:    *cte.Table(),
+		JoinType: _type,
+	}
+}
+
+// ReferenceJoinTable returns a join table that refers to the common table
+// expression, of which the output columns can be referred to (i.e. in its
+// ON clause) but are not selected. This is how the recursive term of a
+// recursive common table expression joins to the common table expression
+// itself, selecting the columns of the other tables
+func (cte *CommonTableExpression) ReferenceJoinTable(_type JoinType) *JoinTable {
+	return &JoinTable{
+		Table:    Table{Name: cte.Name, Columns: cte.outputFields()},
+		JoinType: _type,
+	}
+}
+
+func (cte *CommonTableExpression) sql(b *builder) (string, error) {
+	if cte.Name == "" {
+		return "", fmt.Errorf("Common table expression has no name")
+	}
+
+	var (
+		body string
+		err  error
+	)
+	restore := b.nameOutputs()
+	switch {
+	case cte.Query != nil:
+		body, err = cte.Query.sql(b)
+	case cte.Union != nil:
+		body, err = cte.Union.sql(b)
+	default:
+		err = fmt.Errorf("Common table expression %v has neither a query nor a union", cte.Name)
+	}
+	restore()
+	if err != nil {
+		return "", err
+	}
+
+	sql := insertDoubleQuotes(cte.Name)
+	if len(cte.Columns) > 0 {
+		sql += "(" + delimitQuoted(", ", cte.Columns...) + ")"
+	}
+	sql = delimitSpace(sql, "AS")
+	if hint := cte.Materialized.SQL(); hint != "" {
+		sql = delimitSpace(sql, hint)
+	}
+	return delimitSpace(sql, "(\n"+body+"\n)"), nil
+}
+
+// withSQL returns the WITH clause for the common table expressions. The
+// RECURSIVE keyword applies to the entire clause, and is added when any of
+// the common table expressions is recursive
+func withSQL(b *builder, ctes []*CommonTableExpression) (string, error) {
+	if len(ctes) == 0 {
+		return "", nil
+	}
+
+	keyword := "WITH"
+	sql := ""
+	for i, cte := range ctes {
+		if i > 0 {
+			sql += ", "
+		}
+		if cte.Recursive {
+			keyword = "WITH RECURSIVE"
+		}
+		s, err := cte.sql(b)
+		if err != nil {
+			return "", fmt.Errorf("WITH %v: %v", cte.Name, err)
+		}
+		sql += s
+	}
+	return delimitSpace(keyword, sql), nil
+}
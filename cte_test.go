@@ -0,0 +1,41 @@
+package strata
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecursiveCTEJoinsWithoutSelecting(t *testing.T) {
+	tree := MakeRecursiveCTE("region_tree", "id", "parent_id")
+
+	roots := &Table{Name: "region"}
+	roots.AddFields(NumberField("id"), NumberField("parent_id"))
+	roots.WhereConditions = *roots.FieldByName("parent_id").Where(IsNull, nil)
+	anchor := Query{}
+	anchor.SetBaseTable(roots)
+	*roots.Alias = "r"
+
+	children := &Table{Name: "region"}
+	children.AddFields(NumberField("id"), NumberField("parent_id"))
+	parent := tree.ReferenceJoinTable(InnerJoin)
+	parent.SetLHSField("id").SetEqualTo(children.FieldByName("parent_id"))
+	recursive := Query{}
+	recursive.SetBaseTable(children)
+	*children.Alias = "c"
+	recursive.AddJoinTables(*parent)
+	*recursive.joinTables[0].Alias = "p"
+	tree.Union = MakeUnion(anchor, recursive)
+
+	q := Query{}
+	q.AddWith(tree)
+	q.SetBaseTable(tree.Table())
+
+	got, err := q.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT "c"."id", "c"."parent_id" FROM "region" "c" INNER JOIN "region_tree" "p" ON "p"."id" = "c"."parent_id"`
+	if !strings.Contains(got, want) {
+		t.Errorf("got %v, want it to contain %v", got, want)
+	}
+}
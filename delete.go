@@ -0,0 +1,45 @@
+package strata
+
+import "fmt"
+
+// Delete is an abstraction of a DELETE statement. The rows that are deleted
+// are those that match the conditions of the table and those of each of the
+// join tables
+type Delete struct {
+	mutation
+}
+
+// MakeDelete returns a delete statement on the given table
+func MakeDelete(table *Table) *Delete {
+	return &Delete{mutation: makeMutation(table)}
+}
+
+// SQL returns the SQL representation of the delete statement
+func (del *Delete) SQL() (string, error) {
+	return del.sql(inlineBuilder())
+}
+
+// SQLWithArgs returns the SQL representation of the delete statement with
+// every value replaced by a positional placeholder, along with the values in
+// placeholder order
+func (del *Delete) SQLWithArgs() (string, []interface{}, error) {
+	return sqlWithArgs(del)
+}
+
+func (del *Delete) sql(b *builder) (string, error) {
+	if del == nil || del.table == nil {
+		return "", fmt.Errorf("Delete object is undefined")
+	}
+	defer b.enterScope(del.tables()...)()
+
+	sql := delimitSpace("DELETE FROM", del.table.targetSQL(b))
+
+	using, err := del.fromSQL(b)
+	if err != nil {
+		return "", err
+	}
+	if using != "" {
+		sql = delimitSpace(sql, "USING", using)
+	}
+	return del.tailSQL(b, sql, "delete")
+}
@@ -0,0 +1,197 @@
+package strata
+
+import "fmt"
+
+// Expression is an SQL expression that a field is computed as, in place of
+// the column that it would otherwise refer to. Any aggregate or window
+// function of the field is applied to the expression. The operands of
+// expressions are fields (which are qualified by the aliases of their
+// tables), other expressions, raw SQL fragments, subqueries or values to be
+// bound. Expressions can also be used as the RHS of a where condition
+type Expression interface {
+	expressionSQL(b *builder) (string, error)
+}
+
+// operandsSQL returns the SQL of each of the operands of an expression
+func operandsSQL(b *builder, operands []interface{}) ([]string, error) {
+	sqls := make([]string, len(operands))
+	for i, operand := range operands {
+		s, err := b.operandSQL(operand)
+		if err != nil {
+			return nil, fmt.Errorf("Operand %v: %v", i, err)
+		}
+		sqls[i] = s
+	}
+	return sqls, nil
+}
+
+// functionCall is a call to an SQL function, whose arguments are operands
+// such as fields, raw SQL fragments or values to be bound
+type functionCall struct {
+	name string
+	args []interface{}
+}
+
+func (fc functionCall) expressionSQL(b *builder) (string, error) {
+	args, err := operandsSQL(b, fc.args)
+	if err != nil {
+		return "", fmt.Errorf("%v: %v", fc.name, err)
+	}
+	return fc.name + "(" + delimit(", ", args...) + ")", nil
+}
+
+// Func returns a call to the SQL function with the given name, i.e.
+//
+//	strata.Func("upper", ts.FieldByName("name"))
+func Func(name string, args ...interface{}) Expression {
+	return functionCall{name: name, args: args}
+}
+
+// Coalesce returns the first of the operands that is not null
+func Coalesce(operands ...interface{}) Expression {
+	return Func("COALESCE", operands...)
+}
+
+// NullIf returns null when the operands are equal, and the first operand
+// otherwise
+func NullIf(operand, other interface{}) Expression {
+	return Func("NULLIF", operand, other)
+}
+
+// cast is the conversion of an operand to a type
+type cast struct {
+	operand interface{}
+	_type   string
+}
+
+func (c cast) expressionSQL(b *builder) (string, error) {
+	operand, err := b.operandSQL(c.operand)
+	if err != nil {
+		return "", err
+	}
+	return operand + "::" + c._type, nil
+}
+
+// Cast returns the conversion of the operand to the Postgres type, i.e.
+// "area"::integer
+func Cast(operand interface{}, _type string) Expression {
+	return cast{operand: operand, _type: _type}
+}
+
+// operation is a chain of operands joined by a binary operator, which is
+// parenthesised so that it can be nested in other expressions as is
+type operation struct {
+	operator string
+	operands []interface{}
+}
+
+func (o operation) expressionSQL(b *builder) (string, error) {
+	if len(o.operands) < 2 {
+		return "", fmt.Errorf("Operator %v needs at least two operands", o.operator)
+	}
+	operands, err := operandsSQL(b, o.operands)
+	if err != nil {
+		return "", err
+	}
+	return "(" + delimit(surroundWithSpaces(o.operator), operands...) + ")", nil
+}
+
+// Add returns the sum of the operands
+func Add(operands ...interface{}) Expression {
+	return operation{operator: "+", operands: operands}
+}
+
+// Subtract returns the difference of the operands
+func Subtract(operand, other interface{}) Expression {
+	return operation{operator: "-", operands: []interface{}{operand, other}}
+}
+
+// Multiply returns the product of the operands
+func Multiply(operands ...interface{}) Expression {
+	return operation{operator: "*", operands: operands}
+}
+
+// Divide returns the quotient of the operands, which is truncated when both
+// are integers
+func Divide(operand, other interface{}) Expression {
+	return operation{operator: "/", operands: []interface{}{operand, other}}
+}
+
+// Concat returns the concatenation of the operands as text
+func Concat(operands ...interface{}) Expression {
+	return operation{operator: "||", operands: operands}
+}
+
+// When is a branch of a CASE expression, which results in Then when the
+// condition holds
+type When struct {
+	Condition Condition
+	Then      interface{}
+}
+
+// CaseExpression is a CASE expression, which results in the Then of the
+// first of its branches of which the condition holds, or in Else (which is
+// null when undefined) when none of them hold
+type CaseExpression struct {
+	Whens []When
+	Else  interface{}
+}
+
+// Case returns a CASE expression with the given branches
+func Case(whens ...When) *CaseExpression {
+	return &CaseExpression{Whens: whens}
+}
+
+// Otherwise sets the result of the CASE expression when none of its
+// branches hold
+func (ce *CaseExpression) Otherwise(value interface{}) *CaseExpression {
+	ce.Else = value
+	return ce
+}
+
+func (ce *CaseExpression) expressionSQL(b *builder) (string, error) {
+	if len(ce.Whens) == 0 {
+		return "", fmt.Errorf("CASE expression has no branches")
+	}
+
+	parts := []string{"CASE"}
+	for i, when := range ce.Whens {
+		if when.Condition == nil {
+			return "", fmt.Errorf("Branch %v of the CASE expression has no condition", i)
+		}
+		condition, err := when.Condition.conditionSQL(b)
+		if err != nil {
+			return "", fmt.Errorf("Branch %v of the CASE expression: %v", i, err)
+		}
+		then, err := b.operandSQL(when.Then)
+		if err != nil {
+			return "", fmt.Errorf("Branch %v of the CASE expression: %v", i, err)
+		}
+		parts = append(parts, "WHEN", condition, "THEN", then)
+	}
+	if ce.Else != nil {
+		otherwise, err := b.operandSQL(ce.Else)
+		if err != nil {
+			return "", fmt.Errorf("ELSE of the CASE expression: %v", err)
+		}
+		parts = append(parts, "ELSE", otherwise)
+	}
+	return delimitSpace(append(parts, "END")...), nil
+}
+
+// expressionField returns a field that is computed as the expression, named
+// by the given name
+func expressionField(name string, _type FieldType, expression Expression) TableField {
+	return TableField{
+		Name:       name,
+		Type:       _type,
+		Expression: expression,
+	}
+}
+
+// ExpressionField returns a field that is computed as the expression, which
+// is selected under the given name. Like any other field, it can be compared
+// in where conditions, ordered on and grouped by
+func ExpressionField(name string, _type FieldType, expression Expression) TableField {
+	return expressionField(name, _type, expression)
+}
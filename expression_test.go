@@ -0,0 +1,117 @@
+package strata
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestJoinTableCopiesHaveTheirOwnAlias(t *testing.T) {
+	ts := &Table{Name: "township"}
+	ts.AddFields(NumberField("_id"))
+	q := &Query{}
+	q.SetBaseTable(ts)
+	*ts.Alias = "t"
+
+	erf := JoinTable{Table: Table{Name: "erf"}, JoinType: InnerJoin}
+	erf.AddFields(NumberField("township_id"))
+	erf.SetLHSField("township_id").SetEqualTo(ts.FieldByName("_id"))
+	q.AddJoinTables(erf, erf)
+
+	first, second := *q.joinTables[0].Alias, *q.joinTables[1].Alias
+	if first == second {
+		t.Fatalf("got the same alias %v for both copies", first)
+	}
+	got, err := q.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, alias := range []string{first, second} {
+		want := `INNER JOIN "erf" "` + alias + `" ON "` + alias + `"."township_id" = "t"."_id"`
+		if !strings.Contains(got, want) {
+			t.Errorf("got %v, want it to contain %v", got, want)
+		}
+	}
+}
+
+func TestCorrelatedSubqueryWithACollidingAlias(t *testing.T) {
+	ts := &Table{Name: "township"}
+	ts.AddFields(NumberField("_id"), StringField("name"))
+	erf := &Table{Name: "erf"}
+	erf.AddFields(NumberField("township_id"))
+
+	inner := &Query{}
+	inner.SetBaseTable(erf)
+	*erf.Alias = "t"
+	erf.WhereConditions = *erf.FieldByName("township_id").Where(Equal, ts.FieldByName("_id"))
+
+	q := &Query{}
+	q.SetBaseTable(ts)
+	*ts.Alias = "t"
+	ts.Conditions = Exists(inner)
+	q.AddOrderBy(OrderByField(ts.FieldByName("_id"), Ascending))
+
+	got, err := q.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := regexp.MustCompile(`^SELECT "t"\."_id", "t"\."name" FROM "township" "t" ` +
+		`WHERE EXISTS \(SELECT "(\w+)"\."township_id" FROM "erf" "(\w+)" WHERE "(\w+)"\."township_id" = "t"\."_id"\) ` +
+		`ORDER BY "t"\."_id" ASC$`)
+	match := want.FindStringSubmatch(got)
+	if match == nil || match[1] == "t" || match[1] != match[2] || match[1] != match[3] {
+		t.Errorf("got %v, want it to match %v with the subquery aliased apart from \"t\"", got, want)
+	}
+	if *erf.Alias != "t" {
+		t.Errorf("got alias %v for the table of the subquery, want it to be left as t", *erf.Alias)
+	}
+}
+
+func TestFieldsAreQualifiedByTheAliasOfEachScope(t *testing.T) {
+	ts := &Table{Name: "township"}
+	ts.AddFields(NumberField("_id"), StringField("name"))
+	inner := &Query{}
+	inner.SetBaseTable(ts)
+	q := &Query{}
+	q.SetBaseTable(ts)
+	*ts.Alias = "t"
+	q.AddJoinTables(*MakeSubqueryJoinTable(inner, CrossJoin))
+
+	got, err := q.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := regexp.MustCompile(`^SELECT "t"\."_id", "t"\."name", "\w+"\."_id", "\w+"\."name" FROM "township" "t" ` +
+		`CROSS JOIN \(SELECT "(\w+)"\."_id", "(\w+)"\."name" FROM "township" "(\w+)"\) "\w+"$`)
+	match := want.FindStringSubmatch(got)
+	if match == nil || match[1] == "t" || match[1] != match[2] || match[1] != match[3] {
+		t.Errorf("got %v, want it to match %v with the subquery aliased apart from \"t\"", got, want)
+	}
+}
+
+func TestFieldReferencesSurviveAddFields(t *testing.T) {
+	ts := &Table{Name: "erf"}
+	ts.AddFields(NumberField("_id"), LTreeField("path"), GeometryField("geom"))
+	path, geom := ts.FieldByName("path"), ts.FieldByName("geom")
+	ts.AddFields(NLevel(path), NumberField("area"), NumberField("erf_no"), StringField("zoning"))
+
+	q := &Query{}
+	q.SetBaseTable(ts)
+	*ts.Alias = "e"
+	ts.WhereConditions = *path.Where(LTreeMatches, "za.*{1}")
+	q.AsTile(geom, 0, 0, 0)
+
+	got, err := q.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`nlevel("e"."path") as "nlevel"`,
+		`"e"."path" ~ 'za.*{1}'::lquery`,
+		`ST_AsMVTGeom("e"."geom"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got %v, want it to contain %v", got, want)
+		}
+	}
+}
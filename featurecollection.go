@@ -0,0 +1,129 @@
+package strata
+
+import "fmt"
+
+// featuresAlias is the alias of the rows of a query that is returned as a
+// feature collection
+const featuresAlias = "features"
+
+// maxObjectPairs is the number of key/value pairs that fit the 100 arguments
+// that a Postgres function can be called with
+const maxObjectPairs = 50
+
+// FeatureCollection designates the fields of a query that make up the
+// features of a GeoJSON feature collection. Geometry is the geometry of the
+// features, and ID (if defined) their id. Every other selected field is a
+// property of the features, named by its friendly name (or its name)
+type FeatureCollection struct {
+	Geometry *TableField
+	ID       *TableField
+}
+
+// AsFeatureCollection makes the query return a single row holding a GeoJSON
+// feature collection of its rows, with the geometry field as the geometry of
+// the features. Both fields must be selected by the query, and the id field
+// may be nil
+func (q *Query) AsFeatureCollection(geometry, id *TableField) {
+	q.FeatureCollection = &FeatureCollection{Geometry: geometry, ID: id}
+}
+
+// featureReference returns the reference to the output column of the field
+// from outside of the query
+func featureReference(tf *TableField) string {
+	return chainSelector(featuresAlias, tf.outputName())
+}
+
+// featureGeometrySQL returns the GeoJSON geometry object of a feature, given
+// the selected geometry field. Geometries that are known to be in another
+// spatial reference are transformed to WGS 84, as GeoJSON requires
+func featureGeometrySQL(geometry *TableField) (string, error) {
+	ref := featureReference(geometry)
+	switch geometry.Output.format() {
+	case GeometryGeoJSON:
+		return ref + "::json", nil
+	case GeometryRaw:
+		srid := geometry.SRID
+		if geometry.Output != nil && geometry.Output.SRID != 0 {
+			srid = geometry.Output.SRID
+		}
+		return "ST_AsGeoJSON(" + transformSQL(ref, srid, geoJSONSRID) + ")::json", nil
+	default:
+		return "", fmt.Errorf("Geometry field %v of the feature collection must be selected as is or as GeoJSON", geometry.outputName())
+	}
+}
+
+// propertiesSQL returns the JSON object of the properties of a feature, given
+// its keys and values in turn. Properties that do not fit a single call of
+// json_build_object are built in chunks of jsonb objects that are merged
+func propertiesSQL(properties []string) string {
+	if len(properties) <= 2*maxObjectPairs {
+		return "json_build_object(" + delimit(", ", properties...) + ")"
+	}
+	chunks := []string{}
+	for len(properties) > 0 {
+		n := len(properties)
+		if n > 2*maxObjectPairs {
+			n = 2 * maxObjectPairs
+		}
+		chunks = append(chunks, "jsonb_build_object("+delimit(", ", properties[:n]...)+")")
+		properties = properties[n:]
+	}
+	return "(" + delimit(" || ", chunks...) + ")::json"
+}
+
+// featureCollectionSQL returns the query wrapped so that its rows are
+// aggregated into a single GeoJSON feature collection. An empty result set
+// gives a feature collection without features
+func (q *Query) featureCollectionSQL(b *builder) (string, error) {
+	fc := q.FeatureCollection
+	if fc.Geometry == nil {
+		return "", fmt.Errorf("Feature collection has no geometry field")
+	}
+
+	var (
+		selected   = q.selectedFields()
+		properties = []string{}
+		geometry   *TableField
+		id         = fc.ID == nil
+	)
+	for _, field := range selected {
+		switch {
+		case field.refersTo(fc.Geometry):
+			geometry = field
+			continue
+		case field.refersTo(fc.ID):
+			id = true
+		}
+		properties = append(properties, insertSingleQuotes(field.outputName()), featureReference(field))
+	}
+	if geometry == nil {
+		return "", fmt.Errorf("Geometry field %v of the feature collection is not selected by the query", fc.Geometry.outputName())
+	}
+	if !id {
+		return "", fmt.Errorf("ID field %v of the feature collection is not selected by the query", fc.ID.outputName())
+	}
+
+	geom, err := featureGeometrySQL(geometry)
+	if err != nil {
+		return "", err
+	}
+	feature := []string{"'type'", "'Feature'"}
+	if fc.ID != nil {
+		feature = append(feature, "'id'", featureReference(fc.ID))
+	}
+	feature = append(feature,
+		"'geometry'", geom,
+		"'properties'", propertiesSQL(properties),
+	)
+
+	restore := b.nameOutputs()
+	inner, err := q.selectSQL(b, nil)
+	restore()
+	if err != nil {
+		return "", err
+	}
+	return delimitSpace(
+		"SELECT json_build_object('type', 'FeatureCollection', 'features', COALESCE(json_agg(json_build_object("+delimit(", ", feature...)+")), '[]'::json))",
+		"FROM", "(\n"+inner+"\n)", insertDoubleQuotes(featuresAlias),
+	), nil
+}
@@ -0,0 +1,32 @@
+package strata
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFeaturePropertiesBeyondTheArgumentLimit(t *testing.T) {
+	ts := &Table{Name: "erf"}
+	ts.AddFields(GeometryField("geom"))
+	for i := 0; i < 60; i++ {
+		ts.AddFields(NumberField("p" + strconv.Itoa(i)))
+	}
+	q := &Query{}
+	q.SetBaseTable(ts)
+	q.AsFeatureCollection(ts.FieldByName("geom"), nil)
+
+	got, err := q.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`'properties', (jsonb_build_object('p0', "features"."p0", `,
+		`'p49', "features"."p49") || jsonb_build_object('p50', "features"."p50", `,
+		`'p59', "features"."p59"))::json`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got %v, want it to contain %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package strata
+
+import (
+	"strconv"
+)
+
+// GeometryFormat is the representation that a geometry field is selected in
+type GeometryFormat int
+
+const (
+	// GeometryRaw selects the geometry as is, which Postgres returns as
+	// hex-encoded EWKB
+	GeometryRaw GeometryFormat = iota
+	// GeometryGeoJSON selects the geometry as a GeoJSON geometry object
+	GeometryGeoJSON
+	// GeometryWKT selects the geometry as well-known text
+	GeometryWKT
+	// GeometryEWKT selects the geometry as well-known text prefixed with its
+	// SRID
+	GeometryEWKT
+)
+
+// SQL returns the name of the function that formats the geometry
+func (gf *GeometryFormat) SQL() string {
+	if gf == nil {
+		return ""
+	}
+	switch *gf {
+	case GeometryGeoJSON:
+		return "ST_AsGeoJSON"
+	case GeometryWKT:
+		return "ST_AsText"
+	case GeometryEWKT:
+		return "ST_AsEWKT"
+	case GeometryRaw:
+		fallthrough
+	default:
+		return ""
+	}
+}
+
+// GeometryShape is the part of a geometry that is selected
+type GeometryShape int
+
+const (
+	// WholeGeometry selects the geometry itself
+	WholeGeometry GeometryShape = iota
+	// Centroid selects the centroid of the geometry
+	Centroid
+	// BoundingBox selects the bounding box of the geometry as a polygon
+	BoundingBox
+)
+
+// SQL returns the name of the function that derives the shape
+func (gs *GeometryShape) SQL() string {
+	if gs == nil {
+		return ""
+	}
+	switch *gs {
+	case Centroid:
+		return "ST_Centroid"
+	case BoundingBox:
+		return "ST_Envelope"
+	case WholeGeometry:
+		fallthrough
+	default:
+		return ""
+	}
+}
+
+// GeometryOutput is the way in which a geometry field is selected. The
+// shape is taken first, which is then transformed to the SRID (if defined and
+// different from that of the field), simplified with the tolerance (in the
+// units of the resulting spatial reference) and finally formatted.
+// Precision is the maximum number of decimal digits of GeoJSON coordinates
+type GeometryOutput struct {
+	Format    GeometryFormat `json:"format"`
+	Precision int            `json:"precision"`
+	SRID      int            `json:"srid"`
+	Tolerance float64        `json:"tolerance"`
+	Shape     GeometryShape  `json:"shape"`
+}
+
+// format returns the format that the geometry is selected in, which is
+// GeometryRaw when the output is undefined
+func (gout *GeometryOutput) format() GeometryFormat {
+	if gout == nil {
+		return GeometryRaw
+	}
+	return gout.Format
+}
+
+// apply returns the selector of the geometry as it is output
+func (gout *GeometryOutput) apply(selector string, srid int) string {
+	if gout == nil {
+		return selector
+	}
+	if shape := gout.Shape.SQL(); shape != "" {
+		selector = shape + "(" + selector + ")"
+	}
+	if gout.SRID != 0 && gout.SRID != srid {
+		selector = "ST_Transform(" + selector + ", " + strconv.Itoa(gout.SRID) + ")"
+	}
+	if gout.Tolerance > 0 {
+		selector = "ST_Simplify(" + selector + ", " + strconv.FormatFloat(gout.Tolerance, 'g', -1, 64) + ")"
+	}
+	if format := gout.Format.SQL(); format != "" {
+		if gout.Format == GeometryGeoJSON && gout.Precision > 0 {
+			return format + "(" + selector + ", " + strconv.Itoa(gout.Precision) + ")"
+		}
+		return format + "(" + selector + ")"
+	}
+	return selector
+}
+
+// outputType returns the type of the field as it is selected
+func (tf *TableField) outputType() FieldType {
+	if tf.Output.format() != GeometryRaw {
+		return String
+	}
+	return tf.Type
+}
+
+// OutputGeometry returns a copy of the geometry field that is selected in
+// the given way, i.e. as GeoJSON
+func OutputGeometry(tf TableField, output GeometryOutput) TableField {
+	tf.Output = &output
+	return tf
+}
@@ -0,0 +1,186 @@
+package strata
+
+import "fmt"
+
+// Insert is an abstraction of an INSERT statement. Values are inserted into
+// the columns of the Table, which are the fields of the table that refer
+// to a column as is, unless a subset of them is chosen using SetColumns.
+// The values are either given as rows, or selected using a Query. Conflicts
+// with existing rows can be resolved using OnConflict
+type Insert struct {
+	table     *Table
+	columns   []*TableField
+	returning []*TableField
+	conflict  *Conflict
+	Rows      [][]interface{}
+	Select    *Query
+}
+
+// MakeInsert returns an insert statement into the given table
+func MakeInsert(table *Table) *Insert {
+	table.setRandomAlias()
+	return &Insert{table: table}
+}
+
+// SetColumns chooses the columns that values are inserted into, by the names
+// of the fields of the table
+func (ins *Insert) SetColumns(names ...string) error {
+	columns, err := ins.table.fieldsByName(names...)
+	if err != nil {
+		return err
+	}
+	ins.columns = columns
+	return nil
+}
+
+// AddRow appends a row of values to the insert statement, which are given in
+// the same order as the columns
+func (ins *Insert) AddRow(values ...interface{}) *Insert {
+	ins.Rows = append(ins.Rows, values)
+	return ins
+}
+
+// AddRows appends multiple rows of values to the insert statement
+func (ins *Insert) AddRows(rows ...[]interface{}) *Insert {
+	ins.Rows = append(ins.Rows, rows...)
+	return ins
+}
+
+// SetSelect inserts the rows returned by the query instead of rows of values.
+// The query should select the same number of fields as there are columns
+func (ins *Insert) SetSelect(q *Query) *Insert {
+	ins.Select = q
+	return ins
+}
+
+// SetReturning chooses the fields of the table that are returned for each
+// inserted row, by their names
+func (ins *Insert) SetReturning(names ...string) error {
+	returning, err := ins.table.fieldsByName(names...)
+	if err != nil {
+		return err
+	}
+	ins.returning = returning
+	return nil
+}
+
+func (ins *Insert) assert() error {
+	if ins.table == nil {
+		return fmt.Errorf("Insert object has no table")
+	}
+	if len(ins.Rows) == 0 && ins.Select == nil {
+		return fmt.Errorf("Insert into %v has neither rows nor a select query", ins.table.SQL())
+	}
+	if len(ins.Rows) > 0 && ins.Select != nil {
+		return fmt.Errorf("Insert into %v has both rows and a select query", ins.table.SQL())
+	}
+	if len(ins.insertColumns()) == 0 {
+		return fmt.Errorf("Insert into %v has no columns", ins.table.SQL())
+	}
+	return nil
+}
+
+// insertColumns returns the columns that values are inserted into
+func (ins *Insert) insertColumns() []*TableField {
+	if len(ins.columns) > 0 {
+		return ins.columns
+	}
+	columns := []*TableField{}
+	for i := range ins.table.Fields {
+		if field := &ins.table.Fields[i]; field.isColumn() {
+			columns = append(columns, field)
+		}
+	}
+	return columns
+}
+
+// SQL returns the SQL representation of the insert statement
+func (ins *Insert) SQL() (string, error) {
+	return ins.sql(inlineBuilder())
+}
+
+// SQLWithArgs returns the SQL representation of the insert statement with
+// every value replaced by a positional placeholder, along with the values in
+// placeholder order
+func (ins *Insert) SQLWithArgs() (string, []interface{}, error) {
+	return sqlWithArgs(ins)
+}
+
+func (ins *Insert) sql(b *builder) (string, error) {
+	if ins == nil {
+		return "", fmt.Errorf("Insert object is undefined")
+	}
+	if err := ins.assert(); err != nil {
+		return "", err
+	}
+	defer b.enterScope(ins.table)()
+
+	columns := ins.insertColumns()
+	sql := delimitSpace("INSERT INTO", ins.table.targetSQL(b), "("+columnNamesSQL(columns)+")")
+
+	if ins.Select != nil {
+		selectSQL, err := ins.Select.sql(b)
+		if err != nil {
+			return "", err
+		}
+		sql = delimitSpace(sql, selectSQL)
+	} else {
+		values, err := ins.valuesSQL(b, len(columns))
+		if err != nil {
+			return "", err
+		}
+		sql = delimitSpace(sql, "VALUES", values)
+	}
+
+	if ins.conflict != nil {
+		conflict, err := ins.conflict.sql(b)
+		if err != nil {
+			return "", err
+		}
+		sql = delimitSpace(sql, conflict)
+	}
+
+	if len(ins.returning) > 0 {
+		returning, err := fieldsSQL(b, ins.returning)
+		if err != nil {
+			return "", err
+		}
+		sql = delimitSpace(sql, "RETURNING", returning)
+	}
+	return sql, nil
+}
+
+func (ins *Insert) valuesSQL(b *builder, columns int) (string, error) {
+	sql := ""
+	for i, row := range ins.Rows {
+		if len(row) != columns {
+			return "", fmt.Errorf("Row %v has %v values but %v columns are inserted into", i, len(row), columns)
+		}
+		if i > 0 {
+			sql += ", "
+		}
+		values := make([]string, len(row))
+		for j, value := range row {
+			v, err := b.operandSQL(value)
+			if err != nil {
+				return "", fmt.Errorf("Row %v, column %v: %v", i, j, err)
+			}
+			values[j] = v
+		}
+		sql += "(" + delimit(", ", values...) + ")"
+	}
+	return sql, nil
+}
+
+// columnNamesSQL returns the unqualified names of the columns, as they would
+// appear in the column list of an INSERT statement
+func columnNamesSQL(columns []*TableField) string {
+	sql := ""
+	for i, column := range columns {
+		if i > 0 {
+			sql += ", "
+		}
+		sql += insertDoubleQuotes(column.Name)
+	}
+	return sql
+}
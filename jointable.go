@@ -15,10 +15,17 @@ const (
 	RightJoin
 	// InnerJoin type
 	InnerJoin
-	// OuterJoin type
+	// OuterJoin is a full outer join, which keeps the unmatched rows of both
+	// tables
 	OuterJoin
+	// CrossJoin joins every row to every row of the join table, and has no
+	// ON clause
+	CrossJoin
 )
 
+// FullOuterJoin is the same join as OuterJoin, named after its SQL
+const FullOuterJoin = OuterJoin
+
 // SQL returns the SQL representation of the join type
 func (jt *JoinType) SQL() string {
 	if jt == nil {
@@ -32,14 +39,22 @@ func (jt *JoinType) SQL() string {
 	case LeftJoin:
 		return "LEFT"
 	case OuterJoin:
-		return "OUTER"
+		return "FULL OUTER"
+	case CrossJoin:
+		return "CROSS"
 	default:
 		return ""
 	}
 }
 
 // JoinTable is a table with extra properties, will be appended to
-// the from clause of this statement as a Join
+// the from clause of this statement as a Join. The ON clause is made up of
+// the LHSField ComparisonType RHSField comparison, and the On condition
+// tree, which are joined using the AND keyword when both are present.
+// Instead of an ON clause, the join can be made on the columns in Using that
+// both tables have, or on all of the columns that they have in common when
+// Natural. Lateral join tables are subqueries that can refer to the fields
+// of the preceding tables, i.e. to look up the top rows for every row
 type JoinTable struct {
 	Table
 	// When adding a new property,
@@ -47,18 +62,134 @@ type JoinTable struct {
 	ComparisonType ComparisonType
 	LHSField       *TableField
 	RHSField       *TableField
+	On             Condition
+	Using          []string
+	Natural        bool
+	Lateral        bool
+}
+
+// hasOn returns whether any part of the ON clause is defined
+func (jt *JoinTable) hasOn() bool {
+	return jt.On != nil || jt.LHSField != nil || jt.RHSField != nil
 }
 
+// assert validates the join table, of which the ON clause is required
+// unless the join is a CROSS, NATURAL or USING join, for which it is
+// forbidden
 func (jt *JoinTable) assert() error {
+	name := jt.aliasOrName()
+	if jt.Lateral && jt.Subquery == nil {
+		return fmt.Errorf("Join table %v is lateral but is not a subquery", name)
+	}
+	if jt.Lateral && (jt.JoinType == RightJoin || jt.JoinType == OuterJoin) {
+		// The lateral subquery refers to the preceding tables, which can
+		// therefore not be the side that is kept in full
+		return fmt.Errorf("Lateral join table %v can not be a %v join", name, jt.JoinType.SQL())
+	}
+
+	switch {
+	case jt.JoinType == CrossJoin && (jt.Natural || len(jt.Using) > 0):
+		return fmt.Errorf("Cross join table %v can not be NATURAL or have USING columns", name)
+	case jt.Natural && len(jt.Using) > 0:
+		return fmt.Errorf("Natural join table %v can not have USING columns", name)
+	case jt.JoinType == CrossJoin || jt.Natural || len(jt.Using) > 0:
+		if jt.hasOn() {
+			return fmt.Errorf("Join table %v can not have an ON clause, as it is a %v join", name, jt.kind())
+		}
+		return nil
+	}
+
+	if jt.On != nil && jt.LHSField == nil && jt.RHSField == nil {
+		return nil
+	}
 	if jt.LHSField == nil {
-		return fmt.Errorf("LHSField of join table %v undefined", jt.SQL())
+		return fmt.Errorf("LHSField of join table %v undefined", name)
 	}
 	if jt.RHSField == nil {
-		return fmt.Errorf("RHSField of join table %v undefined", jt.SQL())
+		return fmt.Errorf("RHSField of join table %v undefined", name)
 	}
 	return nil
 }
 
+// kind returns the description of the join that takes no ON clause
+func (jt *JoinTable) kind() string {
+	switch {
+	case jt.JoinType == CrossJoin:
+		return "CROSS"
+	case jt.Natural:
+		return "NATURAL"
+	default:
+		return "USING"
+	}
+}
+
+// sourceSQL returns the table of the join, preceded by LATERAL if the join
+// table is a lateral subquery
+func (jt *JoinTable) sourceSQL(b *builder) (string, error) {
+	t, err := jt.Table.sql(b)
+	if err != nil {
+		return "", err
+	}
+	if jt.Lateral {
+		t = "LATERAL " + t
+	}
+	return t, nil
+}
+
+// joinSQL returns the join of the join table, i.e. LEFT JOIN "t" "a" ON ...
+func (jt *JoinTable) joinSQL(b *builder) (string, error) {
+	t, err := jt.sourceSQL(b)
+	if err != nil {
+		return "", err
+	}
+
+	keyword := jt.JoinType.SQL() + " JOIN"
+	if jt.Natural {
+		keyword = "NATURAL " + keyword
+	}
+	sql := delimitSpace(keyword, t)
+
+	switch {
+	case len(jt.Using) > 0:
+		return delimitSpace(sql, "USING", "("+delimitQuoted(", ", jt.Using...)+")"), nil
+	case jt.JoinType == CrossJoin || jt.Natural:
+		return sql, nil
+	}
+
+	on, err := jt.onCondition().conditionSQL(b)
+	if err != nil {
+		return "", fmt.Errorf("ON clause of join table %v: %v", t, err)
+	}
+	return delimitSpace(sql, "ON", on), nil
+}
+
+// onCondition returns the full ON condition of the join table
+func (jt *JoinTable) onCondition() Condition {
+	on := And()
+	if jt.LHSField != nil && jt.RHSField != nil {
+		on.Append(Where{
+			LHSField:       jt.LHSField,
+			RHSField:       jt.RHSField,
+			ComparisonType: jt.ComparisonType,
+		})
+	}
+	on.Append(andTerms(jt.On)...)
+	return on
+}
+
+// andTerms returns the conditions of an AND group, so that they can be
+// joined to another AND group without being parenthesised, or the condition
+// itself otherwise
+func andTerms(condition Condition) []Condition {
+	if condition == nil {
+		return nil
+	}
+	if cg, ok := condition.(*ConditionGroup); ok && cg != nil && cg.IsInclusive {
+		return cg.Conditions
+	}
+	return []Condition{condition}
+}
+
 // JoinTables is a collection of join tables
 type JoinTables []JoinTable
 
@@ -75,46 +206,42 @@ func (jt *JoinTables) assert() error {
 	return nil
 }
 
-func (jt *JoinTables) fields() TableFields {
-	fields := TableFields{}
-	for _, table := range *jt {
-		fields.append(table.Fields...)
+func (jt *JoinTables) fields() []*TableField {
+	fields := []*TableField{}
+	for i := range *jt {
+		for j := range (*jt)[i].Fields {
+			fields = append(fields, &(*jt)[i].Fields[j])
+		}
 	}
 	return fields
 }
 
-func (jt *JoinTables) wheres() []Wheres {
-	wheres := []Wheres{}
+func (jt *JoinTables) wheres() []Condition {
+	wheres := []Condition{}
 	for _, table := range *jt {
-		wheres = append(wheres, table.WhereConditions)
+		wheres = append(wheres, table.conditions())
 	}
 	return wheres
 }
 
 // SQL returns the SQL representation of the join tables object
 func (jt *JoinTables) SQL() (string, error) {
+	return jt.sql(inlineBuilder())
+}
+
+func (jt *JoinTables) sql(b *builder) (string, error) {
 	if err := jt.assert(); err != nil {
 		return "", err
 	}
 
 	var buf bytes.Buffer
 	buf.Grow(150)
-	for i, table := range *jt {
-		if i > 0 {
-			buf.WriteString(", ")
-		}
-		buf.WriteString(" " + table.JoinType.SQL() + " JOIN ")
-		buf.WriteString(table.SQL())
-		buf.WriteString(" ON ")
-		buf.WriteString(table.LHSField.WhereClauseSQL() + " ")
-		buf.WriteString(table.ComparisonType.SQL() + " ")
-		if !table.ComparisonType.IsExact() {
-			buf.WriteString("'%' + ")
-		}
-		buf.WriteString(table.RHSField.WhereClauseSQL() + " ")
-		if !table.ComparisonType.IsExact() {
-			buf.WriteString(" + '%' ")
+	for _, table := range *jt {
+		join, err := table.joinSQL(b)
+		if err != nil {
+			return "", err
 		}
+		buf.WriteString(" " + join)
 	}
 	return buf.String(), nil
 }
@@ -123,11 +250,61 @@ func (jt *JoinTables) append(tables ...JoinTable) {
 	*jt = append(*jt, tables...)
 }
 
+// add appends the table to the join tables of a statement of which base is
+// the base (or target) table, with a new random alias. A table that is
+// already part of the statement, such as another copy of the same join
+// table, is detached from it first, so that both are rendered as distinct
+// tables
+func (jt *JoinTables) add(base *Table, table JoinTable) {
+	if table.ref != nil && (base != nil && base.ref == table.ref || jt.contains(table.ref)) {
+		table.detach()
+	}
+	table.setRandomAlias()
+	jt.append(table)
+}
+
+// contains reports whether any of the join tables has the given identity
+func (jt *JoinTables) contains(ref *tableRef) bool {
+	for i := range *jt {
+		if (*jt)[i].ref == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// detach gives the join table an identity of its own, along with copies of
+// its fields. The LHSField and the where conditions, which are on the fields
+// of the join table itself, are moved to the copied fields. The RHSField and
+// the Conditions keep referring to the fields that they were made with
+func (jt *JoinTable) detach() {
+	previous := jt.ref
+	jt.ref = &tableRef{}
+	jt.Fields = jt.Fields.detach(previous, jt.ref)
+	jt.Columns = jt.Columns.detach(previous, jt.ref)
+
+	own := func(field *TableField) *TableField {
+		if field == nil || field.table != previous {
+			return field
+		}
+		if f := jt.FieldByName(field.Name); f != nil {
+			return f
+		}
+		return field
+	}
+	jt.LHSField = own(jt.LHSField)
+	wheres := make([]Where, len(jt.WhereConditions.Wheres))
+	for i, where := range jt.WhereConditions.Wheres {
+		where.LHSField = own(where.LHSField)
+		wheres[i] = where
+	}
+	jt.WhereConditions.Wheres = wheres
+}
+
 func (jt *JoinTable) fixFields() {
 	if len(jt.Fields) == 0 {
 		jt.Fields = nil
 	}
-
 	if len(jt.WhereConditions.ToNativeSlice()) == 0 {
 		jt.WhereConditions = Wheres{}
 	}
@@ -168,6 +345,45 @@ func MakeOuterJoinTable(name, schema string) *JoinTable {
 	return makeJoinTable(name, schema, OuterJoin)
 }
 
+// MakeFullOuterJoinTable returns a join table with a FULL OUTER join
+func MakeFullOuterJoinTable(name, schema string) *JoinTable {
+	return makeJoinTable(name, schema, FullOuterJoin)
+}
+
+// MakeCrossJoinTable returns a join table with a CROSS join
+func MakeCrossJoinTable(name, schema string) *JoinTable {
+	return makeJoinTable(name, schema, CrossJoin)
+}
+
+// MakeLateralJoinTable returns a lateral join table of the result set of
+// the query, which can refer to the fields of the preceding tables in its
+// where conditions. Lateral CROSS and INNER joins omit the rows for which the
+// query returns no rows, while LEFT joins keep them, with an ON clause that
+// is TRUE unless another condition is set. RIGHT and FULL OUTER joins can
+// not be lateral
+func MakeLateralJoinTable(q *Query, _type JoinType) *JoinTable {
+	jt := MakeSubqueryJoinTable(q, _type)
+	jt.Lateral = true
+	if _type != CrossJoin {
+		jt.On = Raw("TRUE")
+	}
+	return jt
+}
+
+// SetUsing makes the join on the columns with the given names, which both
+// tables have, instead of on an ON clause
+func (jt *JoinTable) SetUsing(columns ...string) *JoinTable {
+	jt.Using = columns
+	return jt
+}
+
+// SetNatural makes the join on all of the columns that both tables have in
+// common, instead of on an ON clause
+func (jt *JoinTable) SetNatural() *JoinTable {
+	jt.Natural = true
+	return jt
+}
+
 func (jt *JoinTable) setRHSField(field *TableField) {
 	if field == nil {
 		return
@@ -227,6 +443,47 @@ func (jt *JoinTable) SetNotLike(field *TableField) *JoinTable {
 	return jt
 }
 
+// SetLocate is some syntactic sugar for setting the comparison operator to
+// Locate, i.e. joining where the given field is found within the LHSField.
+// Options such as IgnoreCase are given using a Substring in the On condition
+func (jt *JoinTable) SetLocate(field *TableField) *JoinTable {
+	jt.ComparisonType = Locate
+	jt.setRHSField(field)
+	return jt
+}
+
+// SetOn sets the condition tree of the ON clause, which is joined to the
+// LHSField/RHSField comparison (if any) using the AND keyword
+func (jt *JoinTable) SetOn(condition Condition) *JoinTable {
+	jt.On = condition
+	return jt
+}
+
+// AddOn joins the conditions to the condition tree of the ON clause using
+// the AND keyword, i.e. for the further columns of a composite key or for
+// filters on the join table. The conditions are built from the same
+// predicates as where conditions, and can compare to values as well as to
+// the fields of other tables
+func (jt *JoinTable) AddOn(conditions ...Condition) *JoinTable {
+	jt.On = And(append(append([]Condition{}, andTerms(jt.On)...), conditions...)...)
+	return jt
+}
+
+// OnField joins a comparison of the field of the join table with the given
+// name to the ON clause using the AND keyword. The RHS is anything a where
+// condition accepts, such as a field of another table or a value, i.e.
+//
+//	jt.OnField("region_id", strata.Equal, ts.FieldByName("region_id"))
+//	jt.OnField("active", strata.Equal, true)
+func (jt *JoinTable) OnField(name string, comparisonType ComparisonType, rhs interface{}) error {
+	field := jt.FieldByName(name)
+	if field == nil {
+		return fmt.Errorf("Could not find field %v in the JoinTable object", name)
+	}
+	jt.AddOn(Where{LHSField: field, RHSField: rhs, ComparisonType: comparisonType})
+	return nil
+}
+
 // WithFields is a join table wrapper that adds the given fields to the
 func (jt *JoinTable) WithFields(fields ...TableField) *JoinTable {
 	jt.AddFields(fields...)
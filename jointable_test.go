@@ -0,0 +1,26 @@
+package strata
+
+import "testing"
+
+func TestLateralJoinTypes(t *testing.T) {
+	for _, tt := range []struct {
+		joinType JoinType
+		valid    bool
+	}{
+		{LeftJoin, true},
+		{InnerJoin, true},
+		{CrossJoin, true},
+		{RightJoin, false},
+		{FullOuterJoin, false},
+	} {
+		sub := &Table{Name: "valuation"}
+		sub.AddFields(NumberField("value"))
+		q := Query{}
+		q.SetBaseTable(sub)
+
+		err := MakeLateralJoinTable(&q, tt.joinType).assert()
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("%v join: got error %v, want valid %v", tt.joinType.SQL(), err, tt.valid)
+		}
+	}
+}
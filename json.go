@@ -0,0 +1,150 @@
+package strata
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// jsonExtraction is the extraction of a value from a JSON field, either by
+// a key (or array index) or by a path of keys
+type jsonExtraction struct {
+	field    *TableField
+	operator string
+	operand  interface{}
+}
+
+func (je jsonExtraction) expressionSQL(b *builder) (string, error) {
+	field, err := je.field.selectorSQL(b)
+	if err != nil {
+		return "", err
+	}
+	var operand string
+	switch v := je.operand.(type) {
+	case int:
+		// Array indexes are written inline, as a placeholder would be read
+		// as an object key
+		operand = strconv.Itoa(v)
+	case string:
+		operand, err = b.castOperandSQL(v, "text")
+	default:
+		operand, err = b.operandSQL(v)
+	}
+	if err != nil {
+		return "", err
+	}
+	return delimitSpace(field, je.operator, operand), nil
+}
+
+// jsonField returns a field that extracts from the JSON field, named by the
+// friendly name
+func jsonField(tf *TableField, friendlyName string, _type FieldType, operator string, operand interface{}) TableField {
+	field := expressionField(friendlyName, _type, jsonExtraction{field: tf, operator: operator, operand: operand})
+	field.FriendlyName = friendlyName
+	return field
+}
+
+// JSONValueField returns a field that is the JSON value of the key (or array
+// index) of the JSON field, i.e. "attributes" -> 'zoning'
+func JSONValueField(tf *TableField, friendlyName string, key interface{}) TableField {
+	return jsonField(tf, friendlyName, JSON, "->", key)
+}
+
+// JSONTextField returns a field that is the value of the key (or array
+// index) of the JSON field as text, i.e. "attributes" ->> 'zoning'
+func JSONTextField(tf *TableField, friendlyName string, key interface{}) TableField {
+	return jsonField(tf, friendlyName, String, "->>", key)
+}
+
+// JSONPathValueField returns a field that is the JSON value at the path of
+// keys of the JSON field, i.e. "attributes" #> '{zoning,code}'
+func JSONPathValueField(tf *TableField, friendlyName string, path ...string) TableField {
+	return jsonField(tf, friendlyName, JSON, "#>", path)
+}
+
+// JSONPathTextField returns a field that is the value at the path of keys
+// of the JSON field as text, i.e. "attributes" #>> '{zoning,code}'
+func JSONPathTextField(tf *TableField, friendlyName string, path ...string) TableField {
+	return jsonField(tf, friendlyName, String, "#>>", path)
+}
+
+// JSONPathQueryField returns a field that is the result of the SQL/JSON path
+// query on the JSON field, i.e. $.owners[*].name. As jsonb_path_query
+// returns a set, a row is returned for every item that the query matches
+func JSONPathQueryField(tf *TableField, friendlyName string, query string) TableField {
+	field := expressionField(friendlyName, JSON, functionCall{
+		name: "jsonb_path_query",
+		args: []interface{}{tf, jsonPath(query)},
+	})
+	field.FriendlyName = friendlyName
+	return field
+}
+
+// jsonPath is an SQL/JSON path query, which is cast to jsonpath
+type jsonPath string
+
+// IsJSON returns whether or not the comparison type compares JSON values,
+// in which case the RHS is converted as the operator requires
+func (t ComparisonType) IsJSON() bool {
+	switch t {
+	case JSONContains, JSONContainedBy, JSONHasKey, JSONHasAnyKey, JSONHasAllKeys, JSONPathExists, JSONPathMatches:
+		return true
+	default:
+		return false
+	}
+}
+
+// jsonText returns the JSON text of a value, marshalling anything other than
+// strings and raw JSON
+func jsonText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.RawMessage:
+		return string(v), nil
+	case []byte:
+		return string(v), nil
+	default:
+		text, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("Could not marshal %T as JSON: %v", value, err)
+		}
+		return string(text), nil
+	}
+}
+
+// jsonSQL returns the RHS of a JSON comparison. Containment compares to
+// JSON documents, which are marshalled from Go values, the key existence
+// comparisons to keys (or to slices of keys) and the path comparisons to
+// SQL/JSON path queries
+func (w *Where) jsonSQL(b *builder) (string, error) {
+	switch w.RHSField.(type) {
+	case *TableField, Raw, *Query:
+		return b.operandSQL(w.RHSField)
+	}
+
+	switch w.ComparisonType {
+	case JSONContains, JSONContainedBy:
+		text, err := jsonText(w.RHSField)
+		if err != nil {
+			return "", err
+		}
+		return b.castOperandSQL(text, "jsonb")
+	case JSONPathExists, JSONPathMatches:
+		query, ok := w.RHSField.(string)
+		if !ok {
+			return "", fmt.Errorf("Comparison type %v needs a path query, got %T", w.ComparisonType.SQL(), w.RHSField)
+		}
+		return b.castOperandSQL(query, "jsonpath")
+	case JSONHasAnyKey, JSONHasAllKeys:
+		if _, ok := w.RHSField.([]string); !ok {
+			return "", fmt.Errorf("Comparison type %v needs a slice of keys, got %T", w.ComparisonType.SQL(), w.RHSField)
+		}
+		return b.operandSQL(w.RHSField)
+	default:
+		if _, ok := w.RHSField.(string); !ok {
+			return "", fmt.Errorf("Comparison type %v needs a key, got %T", w.ComparisonType.SQL(), w.RHSField)
+		}
+		return b.operandSQL(w.RHSField)
+	}
+}
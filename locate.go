@@ -0,0 +1,83 @@
+package strata
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Substring is the RHS of the Locate comparison, which holds when Value is
+// found within the LHS. Value is anything that can be used as an operand,
+// such as a string or a *TableField. Positions are 1-based, and constrain
+// where the substring is found: Position requires it at exactly that
+// position (i.e. 1 for a prefix), while MinPosition and MaxPosition bound the
+// positions that it may be found at. Any occurrence of the substring counts,
+// not only the first. Substrings are found anywhere in the LHS when no
+// position is given
+type Substring struct {
+	Value       interface{}
+	IgnoreCase  bool
+	Position    int
+	MinPosition int
+	MaxPosition int
+}
+
+// positionSQL returns the position from which the substring is searched
+// for, and the constraint on the result of the search relative to that
+// position, which is 0 when the substring is not found
+func (s *Substring) positionSQL() (int, string, error) {
+	switch {
+	case s.Position < 0 || s.MinPosition < 0 || s.MaxPosition < 0:
+		return 0, "", fmt.Errorf("Substring positions are 1-based and can not be negative")
+	case s.Position > 0 && (s.MinPosition > 0 || s.MaxPosition > 0):
+		return 0, "", fmt.Errorf("Substring can not have both an exact position and a range of positions")
+	case s.MaxPosition > 0 && s.MinPosition > s.MaxPosition:
+		return 0, "", fmt.Errorf("Substring minimum position %v is beyond its maximum position %v", s.MinPosition, s.MaxPosition)
+	case s.Position > 0:
+		return s.Position, "= 1", nil
+	}
+
+	start := s.MinPosition
+	if start == 0 {
+		start = 1
+	}
+	if s.MaxPosition > 0 {
+		return start, delimitSpace("BETWEEN 1 AND", strconv.Itoa(s.MaxPosition-start+1)), nil
+	}
+	return start, "> 0", nil
+}
+
+// locateSQL returns the Locate comparison of the where condition as a
+// position() predicate on the given LHS selector. Substrings that are
+// constrained to later positions are searched for with strpos() in the
+// rest of the LHS from that position, so that occurrences after an earlier
+// one are found too. The RHS is either a Substring, or the value of a
+// Substring without any options
+func (w *Where) locateSQL(b *builder, lhs string) (string, error) {
+	substring, ok := w.RHSField.(Substring)
+	if !ok {
+		if s, isPtr := w.RHSField.(*Substring); isPtr && s != nil {
+			substring = *s
+		} else {
+			substring = Substring{Value: w.RHSField}
+		}
+	}
+	if substring.Value == nil {
+		return "", fmt.Errorf("No right hand field object provided - is necessary for comparison type")
+	}
+
+	start, constraint, err := substring.positionSQL()
+	if err != nil {
+		return "", err
+	}
+	rhs, err := b.operandSQL(substring.Value)
+	if err != nil {
+		return "", fmt.Errorf("Right hand side of Locate comparison: %v", err)
+	}
+	if substring.IgnoreCase {
+		lhs, rhs = "lower("+lhs+")", "lower("+rhs+")"
+	}
+	if start > 1 {
+		return delimitSpace("strpos(substr("+lhs+", "+strconv.Itoa(start)+"), "+rhs+")", constraint), nil
+	}
+	return delimitSpace("position("+rhs+" in "+lhs+")", constraint), nil
+}
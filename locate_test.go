@@ -0,0 +1,37 @@
+package strata
+
+import "testing"
+
+func TestLocateFindsLaterOccurrences(t *testing.T) {
+	name := StringField("name")
+	tests := []struct {
+		name      string
+		substring Substring
+		want      string
+	}{
+		{name: "anywhere", substring: Substring{Value: "-"}, want: `position('-' in "name") > 0`},
+		{name: "prefix", substring: Substring{Value: "-", Position: 1}, want: `position('-' in "name") = 1`},
+		{name: "exact position", substring: Substring{Value: "-", Position: 4}, want: `strpos(substr("name", 4), '-') = 1`},
+		{name: "minimum position", substring: Substring{Value: "-", MinPosition: 3}, want: `strpos(substr("name", 3), '-') > 0`},
+		{name: "range of positions", substring: Substring{Value: "-", MinPosition: 3, MaxPosition: 6}, want: `strpos(substr("name", 3), '-') BETWEEN 1 AND 4`},
+		{name: "maximum position", substring: Substring{Value: "-", MaxPosition: 6}, want: `position('-' in "name") BETWEEN 1 AND 6`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&Where{LHSField: &name, RHSField: tt.substring, ComparisonType: Locate}).sql(inlineBuilder())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocateHasNoOperator(t *testing.T) {
+	locate := Locate
+	if got := locate.SQL(); got != "" {
+		t.Errorf("got operator %v, want none", got)
+	}
+}
@@ -0,0 +1,28 @@
+package strata
+
+// NLevel returns a field that is the number of labels in the path of the
+// given ltree field
+func NLevel(tf *TableField) TableField {
+	return expressionField("nlevel", Number, functionCall{name: "nlevel", args: []interface{}{tf}})
+}
+
+// Subpath returns a field that is the subpath of the given ltree field
+// starting at the offset (which counts from the end when negative). The
+// subpath extends to the end of the path, unless a length is given
+func Subpath(tf *TableField, offset int, length ...int) TableField {
+	args := []interface{}{tf, offset}
+	if len(length) > 0 {
+		args = append(args, length[0])
+	}
+	return expressionField("subpath", LTree, functionCall{name: "subpath", args: args})
+}
+
+// LCA returns a field that is the longest common ancestor of the paths of
+// the given ltree fields
+func LCA(fields ...*TableField) TableField {
+	args := make([]interface{}, len(fields))
+	for i, field := range fields {
+		args[i] = field
+	}
+	return expressionField("lca", LTree, functionCall{name: "lca", args: args})
+}
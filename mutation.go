@@ -0,0 +1,123 @@
+package strata
+
+import "fmt"
+
+// mutation holds what UPDATE and DELETE statements have in common - the
+// target table, the tables that are joined to it in the FROM/USING clause,
+// the WHERE clause and the RETURNING clause
+type mutation struct {
+	table      *Table
+	joinTables JoinTables
+	returning  []*TableField
+	// AllowUnfiltered has to be set for the statement to be created without
+	// a WHERE clause, as it would affect every row of the table
+	AllowUnfiltered bool
+}
+
+func makeMutation(table *Table) mutation {
+	table.setRandomAlias()
+	return mutation{table: table}
+}
+
+// AddJoinTables appends tables to the FROM/USING clause of the statement.
+// Their ON conditions are added to the WHERE clause
+func (m *mutation) AddJoinTables(tables ...JoinTable) {
+	for _, table := range tables {
+		m.joinTables.add(m.table, table)
+	}
+}
+
+// SetReturning chooses the fields of the target table that are returned for
+// each affected row, by their names
+func (m *mutation) SetReturning(names ...string) error {
+	returning, err := m.table.fieldsByName(names...)
+	if err != nil {
+		return err
+	}
+	m.returning = returning
+	return nil
+}
+
+// fromSQL returns the comma separated list of join tables. Only inner and
+// cross joins are equivalent to filtering the cartesian product, so outer
+// joins are rejected
+func (m *mutation) fromSQL(b *builder) (string, error) {
+	if err := m.joinTables.assert(); err != nil {
+		return "", err
+	}
+	sql := ""
+	for i, table := range m.joinTables {
+		if i > 0 {
+			sql += ", "
+		}
+		switch {
+		case table.Natural || len(table.Using) > 0:
+			return "", fmt.Errorf("Join table %v is a %v join, which can not be moved into the WHERE clause", table.aliasOrName(), table.kind())
+		case table.JoinType != InnerJoin && table.JoinType != CrossJoin:
+			return "", fmt.Errorf("Join table %v is a %v join, which can not be moved into the WHERE clause", table.aliasOrName(), table.JoinType.SQL())
+		}
+		t, err := table.sourceSQL(b)
+		if err != nil {
+			return "", err
+		}
+		sql += t
+	}
+	return sql, nil
+}
+
+// tables returns the target table and the join tables of the statement
+func (m *mutation) tables() []*Table {
+	tables := []*Table{m.table}
+	for i := range m.joinTables {
+		tables = append(tables, &m.joinTables[i].Table)
+	}
+	return tables
+}
+
+// whereSQL returns the WHERE clause of the statement, which is made up of the
+// ON conditions of the join tables and of the where conditions of the tables.
+// Unlike those of a Query, the conditions of the tables are joined using the
+// AND keyword, so that filtering a join table narrows the rows that are
+// affected rather than widening them
+func (m *mutation) whereSQL(b *builder, verb string) (string, error) {
+	m.table.fixFields()
+	m.joinTables.fixFields()
+
+	where := And()
+	for i := range m.joinTables {
+		where.Append(m.joinTables[i].onCondition())
+	}
+	where.Append(m.table.conditions())
+	where.Append(m.joinTables.wheres()...)
+
+	restore := b.filterRows(true)
+	sql, err := where.conditionSQL(b)
+	restore()
+	if err != nil {
+		return "", err
+	}
+	if sql == "" && !m.AllowUnfiltered {
+		return "", fmt.Errorf("Refusing to %v every row of %v without a WHERE clause - set AllowUnfiltered to allow it", verb, m.table.SQL())
+	}
+	return sql, nil
+}
+
+// tailSQL appends the WHERE and RETURNING clauses to the statement
+func (m *mutation) tailSQL(b *builder, sql, verb string) (string, error) {
+	where, err := m.whereSQL(b, verb)
+	if err != nil {
+		return "", err
+	}
+	if where != "" {
+		sql = delimitSpace(sql, "WHERE", where)
+	}
+
+	if len(m.returning) > 0 {
+		returning, err := fieldsSQL(b, m.returning)
+		if err != nil {
+			return "", err
+		}
+		sql = delimitSpace(sql, "RETURNING", returning)
+	}
+	return sql, nil
+}
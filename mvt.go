@@ -0,0 +1,203 @@
+package strata
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// webMercatorSRID is the spatial reference of vector tiles
+	webMercatorSRID = 3857
+	// tileAlias is the alias of the rows of a query that is returned as a
+	// vector tile
+	tileAlias = "tile"
+	// defaultTileExtent is the size of a tile in tile coordinates
+	defaultTileExtent = 4096
+	// defaultTileBuffer is the size of the buffer around a tile in tile
+	// coordinates, which features are clipped to
+	defaultTileBuffer = 256
+)
+
+// TileZoom holds the settings of a tile for the zoom levels up to MaxZoom.
+// Tolerance simplifies the geometries (in metres) before they are clipped to
+// the tile, and Limit caps the number of features of the tile
+type TileZoom struct {
+	MaxZoom   int
+	Tolerance float64
+	Limit     int
+}
+
+// Tile is the Mapbox vector tile at the Z/X/Y coordinates that a query
+// returns its rows as. Geometry is the geometry field of the base table,
+// which is clipped to the tile, while every other selected field is an
+// attribute of the features. Its SRID is taken to be 3857 unless defined.
+// Extent and Buffer default to 4096 and 256. Zooms are in ascending order of
+// MaxZoom, the first of which that covers Z applies to the tile
+type Tile struct {
+	Z        int
+	X        int
+	Y        int
+	Layer    string
+	Geometry *TableField
+	Extent   int
+	Buffer   int
+	Zooms    []TileZoom
+}
+
+// AsTile makes the query return a single row holding the Mapbox vector tile
+// at the Z/X/Y coordinates, of which the features are the rows of the query.
+// The features are limited to those of which the geometry field (which must
+// belong to the base table) intersects the tile. The layer is named after
+// the base table, unless changed on the returned tile
+func (q *Query) AsTile(geometry *TableField, z, x, y int) *Tile {
+	q.Tile = &Tile{Z: z, X: x, Y: y, Geometry: geometry}
+	return q.Tile
+}
+
+func (t *Tile) assert() error {
+	if t.Geometry == nil {
+		return fmt.Errorf("Tile has no geometry field")
+	}
+	if t.Z < 0 || t.Z > 30 {
+		return fmt.Errorf("Tile zoom level %v is out of range", t.Z)
+	}
+	if max := 1 << uint(t.Z); t.X < 0 || t.X >= max || t.Y < 0 || t.Y >= max {
+		return fmt.Errorf("Tile %v/%v/%v is out of range", t.Z, t.X, t.Y)
+	}
+	return nil
+}
+
+// zoom returns the settings that apply to the zoom level of the tile
+func (t *Tile) zoom() TileZoom {
+	for _, zoom := range t.Zooms {
+		if t.Z <= zoom.MaxZoom {
+			return zoom
+		}
+	}
+	return TileZoom{}
+}
+
+func (t *Tile) extent() int {
+	if t.Extent > 0 {
+		return t.Extent
+	}
+	return defaultTileExtent
+}
+
+func (t *Tile) buffer() int {
+	if t.Buffer > 0 {
+		return t.Buffer
+	}
+	return defaultTileBuffer
+}
+
+// envelopeSQL returns the bounds of the tile, extended by the given margin
+// (a fraction of the size of the tile)
+func (t *Tile) envelopeSQL(margin float64) string {
+	args := []string{strconv.Itoa(t.Z), strconv.Itoa(t.X), strconv.Itoa(t.Y)}
+	if margin > 0 {
+		args = append(args, "margin => "+strconv.FormatFloat(margin, 'g', -1, 64))
+	}
+	return "ST_TileEnvelope(" + delimit(", ", args...) + ")"
+}
+
+// condition returns the filter on the geometry field, which is compared to
+// the tile (including its buffer) in its own spatial reference so that its
+// index can be used
+func (t *Tile) condition() Condition {
+	margin := float64(t.buffer()) / float64(t.extent())
+	return Where{
+		LHSField:       t.Geometry,
+		RHSField:       Raw(transformSQL(t.envelopeSQL(margin), webMercatorSRID, t.Geometry.SRID)),
+		ComparisonType: BBoxIntersects,
+	}
+}
+
+// geometrySQL returns the geometry of a feature (the selected geometry field
+// of the tile), transformed to the spatial reference of the tile and clipped
+// to it
+func (t *Tile) geometrySQL(b *builder, geometry *TableField) (string, error) {
+	selector, err := geometry.selectorSQL(b)
+	if err != nil {
+		return "", err
+	}
+	selector = transformSQL(selector, geometry.SRID, webMercatorSRID)
+	if tolerance := t.zoom().Tolerance; tolerance > 0 {
+		selector = "ST_Simplify(" + selector + ", " + strconv.FormatFloat(tolerance, 'g', -1, 64) + ")"
+	}
+	return "ST_AsMVTGeom(" + delimit(", ",
+		selector,
+		t.envelopeSQL(0),
+		strconv.Itoa(t.extent()),
+		strconv.Itoa(t.buffer()),
+		"true",
+	) + ") as " + insertDoubleQuotes(geometry.outputName()), nil
+}
+
+// tileSQL returns the query wrapped so that its rows are encoded as the
+// features of a single vector tile
+func (q *Query) tileSQL(b *builder) (string, error) {
+	t := q.Tile
+	if err := t.assert(); err != nil {
+		return "", err
+	}
+
+	selected := q.selectedFields()
+	var geometry *TableField
+	for i := range q.baseTable.Fields {
+		if q.baseTable.Fields[i].refersTo(t.Geometry) {
+			geometry = &q.baseTable.Fields[i]
+		}
+	}
+	if geometry == nil {
+		return "", fmt.Errorf("Geometry field %v of the tile is not selected from the base table", t.Geometry.outputName())
+	}
+
+	fields := func(b *builder) (string, error) {
+		sql := ""
+		for i, field := range selected {
+			if i > 0 {
+				sql += ", "
+			}
+			var (
+				s   string
+				err error
+			)
+			if field == geometry {
+				s, err = t.geometrySQL(b, geometry)
+			} else {
+				s, err = field.sql(b)
+			}
+			if err != nil {
+				return "", fmt.Errorf("Field %v: %v", field.outputName(), err)
+			}
+			sql += s
+		}
+		return sql, nil
+	}
+
+	restore := b.nameOutputs()
+	inner, err := q.selectSQL(b, &selectOverrides{
+		fields:    fields,
+		condition: t.condition(),
+		limit:     t.zoom().Limit,
+	})
+	restore()
+	if err != nil {
+		return "", err
+	}
+
+	layer := t.Layer
+	if layer == "" {
+		layer = q.baseTable.Name
+	}
+	return delimitSpace(
+		"SELECT ST_AsMVT("+delimit(", ",
+			insertDoubleQuotes(tileAlias)+".*",
+			insertSingleQuotes(layer),
+			strconv.Itoa(t.extent()),
+			insertSingleQuotes(t.Geometry.outputName()),
+		)+")",
+		"FROM", "(\n"+inner+"\n)", insertDoubleQuotes(tileAlias),
+	), nil
+}
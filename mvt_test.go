@@ -0,0 +1,30 @@
+package strata
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTileFilterPrecedence(t *testing.T) {
+	ts := &Table{Name: "township", Schema: "cadastral"}
+	ts.AddFields(NumberField("_id"), StringField("name"), GeometryField("geom"))
+	q := &Query{}
+	q.SetBaseTable(ts)
+	*ts.Alias = "t"
+
+	name := ts.FieldByName("name")
+	ts.WhereConditions = Wheres{Wheres: []Where{
+		{LHSField: name, RHSField: "north", ComparisonType: Equal},
+		{LHSField: name, RHSField: "south", ComparisonType: Equal},
+	}}
+	q.AsTile(ts.FieldByName("geom"), 0, 0, 0)
+
+	got, err := q.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `WHERE ("t"."name" = 'north' OR "t"."name" = 'south') AND "t"."geom" && `
+	if !strings.Contains(got, want) {
+		t.Errorf("got %v, want it to contain %v", got, want)
+	}
+}
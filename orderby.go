@@ -0,0 +1,137 @@
+package strata
+
+import "fmt"
+
+// SortDirection is the direction in which an ordering is applied
+type SortDirection int
+
+const (
+	// Ascending orders from the smallest to the largest value
+	Ascending SortDirection = iota
+	// Descending orders from the largest to the smallest value
+	Descending
+)
+
+// SQL returns the SQL representation of the sort direction
+func (sd *SortDirection) SQL() string {
+	if sd != nil && *sd == Descending {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// NullsOrder is the placement of null values within an ordering
+type NullsOrder int
+
+const (
+	// NullsDefault leaves the placement of nulls to Postgres, which places
+	// them last when ascending and first when descending
+	NullsDefault NullsOrder = iota
+	// NullsFirst places null values before all other values
+	NullsFirst
+	// NullsLast places null values after all other values
+	NullsLast
+)
+
+// SQL returns the SQL representation of the nulls placement
+func (no *NullsOrder) SQL() string {
+	if no == nil {
+		return ""
+	}
+	switch *no {
+	case NullsFirst:
+		return "NULLS FIRST"
+	case NullsLast:
+		return "NULLS LAST"
+	default:
+		return ""
+	}
+}
+
+// OrderBy is a single element of an ORDER BY clause. The ordering is made on
+// the first of Field, FriendlyName or Expression that is defined
+type OrderBy struct {
+	Field        *TableField
+	FriendlyName string
+	Expression   string
+	Direction    SortDirection
+	Nulls        NullsOrder
+}
+
+// OrderBys is an ordered collection of orderings
+type OrderBys []OrderBy
+
+// OrderByField returns an ordering on the given field, which is resolved
+// using the alias of its table when the SQL is created
+func OrderByField(field *TableField, direction SortDirection) OrderBy {
+	return OrderBy{Field: field, Direction: direction}
+}
+
+// OrderByFriendlyName returns an ordering on the output column with the
+// given friendly name
+func OrderByFriendlyName(friendlyName string, direction SortDirection) OrderBy {
+	return OrderBy{FriendlyName: friendlyName, Direction: direction}
+}
+
+// OrderByExpression returns an ordering on an arbitrary SQL expression,
+// which is written to the query as is
+func OrderByExpression(expression string, direction SortDirection) OrderBy {
+	return OrderBy{Expression: expression, Direction: direction}
+}
+
+// subjectSQL returns the SQL of the value being ordered on. Orderings on
+// the outer level of a union can only refer to the output columns, so
+// fields are referred to by their friendly name (or their name) instead
+func (o *OrderBy) subjectSQL(b *builder, outer bool) (string, error) {
+	switch {
+	case o.Field != nil && outer && o.Field.FriendlyName != "":
+		return insertDoubleQuotes(o.Field.FriendlyName), nil
+	case o.Field != nil && outer:
+		return insertDoubleQuotes(o.Field.Name), nil
+	case o.Field != nil:
+		return o.Field.selectorSQL(b)
+	case o.FriendlyName != "":
+		return insertDoubleQuotes(o.FriendlyName), nil
+	case o.Expression != "":
+		return o.Expression, nil
+	default:
+		return "", fmt.Errorf("Ordering has no field, friendly name or expression")
+	}
+}
+
+func (o *OrderBy) sql(b *builder, outer bool) (string, error) {
+	subject, err := o.subjectSQL(b, outer)
+	if err != nil {
+		return "", err
+	}
+	sql := delimitSpace(subject, o.Direction.SQL())
+	if nulls := o.Nulls.SQL(); nulls != "" {
+		sql = delimitSpace(sql, nulls)
+	}
+	return sql, nil
+}
+
+func (ob *OrderBys) append(orderings ...OrderBy) {
+	*ob = append(*ob, orderings...)
+}
+
+// SQL returns the SQL representation of the orderings, without the
+// ORDER BY keywords
+func (ob *OrderBys) SQL() (string, error) {
+	return ob.sql(inlineBuilder(), false)
+}
+
+func (ob *OrderBys) sql(b *builder, outer bool) (string, error) {
+	sql := ""
+	for i, ordering := range *ob {
+		if i > 0 {
+			sql += ", "
+		}
+		s, err := ordering.sql(b, outer)
+		if err != nil {
+			return "", fmt.Errorf("Ordering %v: %v", i, err)
+		}
+		sql += s
+	}
+	return sql, nil
+}
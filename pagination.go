@@ -0,0 +1,119 @@
+package strata
+
+import "fmt"
+
+// keysetCondition is the predicate that seeks past the last row of the
+// previous page, given the values of that row for each of the orderings
+type keysetCondition struct {
+	selectors  []string
+	directions []SortDirection
+	values     []interface{}
+}
+
+// SeekAfter sets the values of the ORDER BY fields of the last row of the
+// previous page, so that the query returns the rows that follow it without
+// having to scan past them using OFFSET. The values are given in the same
+// order as the orderings of the query. The ordering should be unique and
+// should not include null values, as rows with nulls can not be compared
+func (q *Query) SeekAfter(values ...interface{}) {
+	q.Keyset = values
+}
+
+// keysetCondition returns the seek predicate of the query, if any
+func (q *Query) keysetCondition(b *builder) (Condition, error) {
+	if len(q.Keyset) == 0 {
+		return nil, nil
+	}
+	if len(q.Keyset) != len(q.Ordering) {
+		return nil, fmt.Errorf("Keyset has %v values but the query is ordered by %v fields", len(q.Keyset), len(q.Ordering))
+	}
+
+	fields := q.selectedFields()
+	kc := &keysetCondition{values: q.Keyset}
+	for i, ordering := range q.Ordering {
+		selector, err := ordering.predicateSQL(b, fields)
+		if err != nil {
+			return nil, fmt.Errorf("Ordering %v: %v", i, err)
+		}
+		kc.selectors = append(kc.selectors, selector)
+		kc.directions = append(kc.directions, ordering.Direction)
+	}
+	return kc, nil
+}
+
+// predicateSQL returns the SQL of the ordering as it would appear in a
+// WHERE clause - where the output columns can not be referred to, so
+// friendly names are resolved to the selector of the field
+func (o *OrderBy) predicateSQL(b *builder, fields []*TableField) (string, error) {
+	if o.Field != nil || o.FriendlyName == "" {
+		return o.subjectSQL(b, false)
+	}
+	for _, field := range fields {
+		if field.FriendlyName == o.FriendlyName {
+			return field.selectorSQL(b)
+		}
+	}
+	return "", fmt.Errorf("Could not find a field with friendly name %v", o.FriendlyName)
+}
+
+func (kc *keysetCondition) mixedDirections() bool {
+	for _, direction := range kc.directions {
+		if direction != kc.directions[0] {
+			return true
+		}
+	}
+	return false
+}
+
+func (kc *keysetCondition) operatorSQL(b *builder) (string, string, error) {
+	sql, err := kc.conditionSQL(b)
+	if err != nil || !kc.mixedDirections() || len(kc.selectors) < 2 {
+		return sql, "", err
+	}
+	return sql, "OR", nil
+}
+
+func seekOperator(direction SortDirection) string {
+	if direction == Descending {
+		return "<"
+	}
+	return ">"
+}
+
+// conditionSQL renders a single row value comparison when all orderings
+// run in the same direction, i.e. (a, b) > ($1, $2), which can make use of
+// a composite index. Mixed directions are expanded into the equivalent
+// (a > $1) OR (a = $1 AND b < $2)
+func (kc *keysetCondition) conditionSQL(b *builder) (string, error) {
+	values := make([]string, len(kc.values))
+	for i, value := range kc.values {
+		v, err := b.bind(value)
+		if err != nil {
+			return "", fmt.Errorf("Keyset value %v: %v", i, err)
+		}
+		values[i] = v
+	}
+
+	if len(kc.selectors) == 1 {
+		return delimitSpace(kc.selectors[0], seekOperator(kc.directions[0]), values[0]), nil
+	}
+
+	if !kc.mixedDirections() {
+		return delimitSpace(
+			"("+delimit(", ", kc.selectors...)+")",
+			seekOperator(kc.directions[0]),
+			"("+delimit(", ", values...)+")",
+		), nil
+	}
+
+	terms := make([]string, len(kc.selectors))
+	for i := range kc.selectors {
+		term := ""
+		for j := 0; j < i; j++ {
+			term += delimitSpace(kc.selectors[j], "=", values[j]) + " AND "
+		}
+		term += delimitSpace(kc.selectors[i], seekOperator(kc.directions[i]), values[i])
+		terms[i] = "(" + term + ")"
+	}
+	return delimit(" OR ", terms...), nil
+}
@@ -0,0 +1,28 @@
+package strata
+
+import "testing"
+
+func TestKeysetPagingPrecedence(t *testing.T) {
+	ts := &Table{Name: "township", Schema: "cadastral"}
+	ts.AddFields(NumberField("_id"), StringField("name"))
+	q := &Query{}
+	q.SetBaseTable(ts)
+	*ts.Alias = "t"
+
+	name := ts.FieldByName("name")
+	ts.WhereConditions = Wheres{Wheres: []Where{
+		{LHSField: name, RHSField: "north", ComparisonType: Equal},
+		{LHSField: name, RHSField: "south", ComparisonType: Equal},
+	}}
+	q.AddOrderBy(OrderByField(ts.FieldByName("_id"), Ascending))
+	q.SeekAfter(10)
+
+	got, err := q.NestedWheres()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `("t"."name" = 'north' OR "t"."name" = 'south') AND "t"."_id" > 10`
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
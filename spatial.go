@@ -0,0 +1,177 @@
+package strata
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// geoJSONSRID is the spatial reference of GeoJSON geometries, which are
+// always in WGS 84 longitude/latitude
+const geoJSONSRID = 4326
+
+// WKT is a geometry given as well-known text, i.e. POINT(18.42 -33.92). When
+// the SRID is undefined, the geometry is taken to be in the spatial reference
+// of the field that it is compared to
+type WKT struct {
+	Text string
+	SRID int
+}
+
+// GeoJSON is a geometry given as a GeoJSON geometry object. Its SRID is 4326
+// unless defined otherwise
+type GeoJSON struct {
+	Text string
+	SRID int
+}
+
+// Envelope is a bounding box geometry. When the SRID is undefined, the
+// envelope is taken to be in the spatial reference of the field that it is
+// compared to
+type Envelope struct {
+	XMin float64
+	YMin float64
+	XMax float64
+	YMax float64
+	SRID int
+}
+
+// WithinDistance is the RHS of the STDWithin comparison, which holds when the
+// LHS is within the distance of the geometry. The distance is in the units
+// of the spatial reference of the LHS
+type WithinDistance struct {
+	Geometry interface{}
+	Distance float64
+}
+
+// geometrySQL returns the SQL of a geometry on the RHS of a spatial
+// comparison, which is transformed to the target spatial reference when its
+// own is known to differ. Strings are read as well-known text, while fields,
+// raw SQL fragments and subqueries are used as is
+func geometrySQL(b *builder, value interface{}, target int) (string, error) {
+	var (
+		sql  string
+		srid int
+		err  error
+	)
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("No geometry provided")
+	case *TableField:
+		sql, err = v.selectorSQL(b)
+		srid = v.SRID
+	case string:
+		sql, srid, err = wktSQL(b, WKT{Text: v}, target)
+	case WKT:
+		sql, srid, err = wktSQL(b, v, target)
+	case *WKT:
+		sql, srid, err = wktSQL(b, *v, target)
+	case GeoJSON:
+		sql, srid, err = geoJSONSQL(b, v)
+	case *GeoJSON:
+		sql, srid, err = geoJSONSQL(b, *v)
+	case Envelope:
+		sql, srid, err = envelopeSQL(b, v, target)
+	case *Envelope:
+		sql, srid, err = envelopeSQL(b, *v, target)
+	case Raw, *Query:
+		sql, err = b.operandSQL(v)
+	default:
+		return "", fmt.Errorf("Unsupported geometry of Go type %T", value)
+	}
+	if err != nil {
+		return "", err
+	}
+	return transformSQL(sql, srid, target), nil
+}
+
+// transformSQL wraps the geometry in ST_Transform when both spatial
+// references are known and differ
+func transformSQL(sql string, srid, target int) string {
+	if srid == 0 || target == 0 || srid == target {
+		return sql
+	}
+	return "ST_Transform(" + sql + ", " + strconv.Itoa(target) + ")"
+}
+
+func wktSQL(b *builder, wkt WKT, target int) (string, int, error) {
+	text, err := b.bind(wkt.Text)
+	if err != nil {
+		return "", 0, err
+	}
+	srid := wkt.SRID
+	if srid == 0 {
+		srid = target
+	}
+	if srid == 0 {
+		return "ST_GeomFromText(" + text + ")", 0, nil
+	}
+	return "ST_GeomFromText(" + text + ", " + strconv.Itoa(srid) + ")", srid, nil
+}
+
+func geoJSONSQL(b *builder, geoJSON GeoJSON) (string, int, error) {
+	text, err := b.bind(geoJSON.Text)
+	if err != nil {
+		return "", 0, err
+	}
+	srid := geoJSON.SRID
+	if srid == 0 {
+		srid = geoJSONSRID
+	}
+	return "ST_SetSRID(ST_GeomFromGeoJSON(" + text + "), " + strconv.Itoa(srid) + ")", srid, nil
+}
+
+func envelopeSQL(b *builder, env Envelope, target int) (string, int, error) {
+	bounds := []float64{env.XMin, env.YMin, env.XMax, env.YMax}
+	args := make([]string, 0, len(bounds)+1)
+	for _, bound := range bounds {
+		s, err := b.bind(bound)
+		if err != nil {
+			return "", 0, err
+		}
+		args = append(args, s)
+	}
+	srid := env.SRID
+	if srid == 0 {
+		srid = target
+	}
+	if srid != 0 {
+		args = append(args, strconv.Itoa(srid))
+	}
+	return "ST_MakeEnvelope(" + delimit(", ", args...) + ")", srid, nil
+}
+
+// spatialSQL returns the spatial comparison of the where condition on the
+// given LHS selector. The bounding box comparison is an operator, while the
+// others are function calls
+func (w *Where) spatialSQL(b *builder, lhs string) (string, error) {
+	value := w.RHSField
+	var distance *float64
+	if w.ComparisonType == STDWithin {
+		switch v := value.(type) {
+		case WithinDistance:
+			value, distance = v.Geometry, &v.Distance
+		case *WithinDistance:
+			value, distance = v.Geometry, &v.Distance
+		default:
+			return "", fmt.Errorf("Comparison type %v needs a WithinDistance, got %T", w.ComparisonType.SQL(), value)
+		}
+	}
+
+	rhs, err := geometrySQL(b, value, w.LHSField.SRID)
+	if err != nil {
+		return "", fmt.Errorf("Right hand side of %v comparison: %v", w.ComparisonType.SQL(), err)
+	}
+	if w.ComparisonType == BBoxIntersects {
+		return delimitSpace(lhs, w.ComparisonType.SQL(), rhs), nil
+	}
+
+	args := []string{lhs, rhs}
+	if distance != nil {
+		d, err := b.bind(*distance)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, d)
+	}
+	return w.ComparisonType.SQL() + "(" + delimit(", ", args...) + ")", nil
+}
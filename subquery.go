@@ -0,0 +1,76 @@
+package strata
+
+import "fmt"
+
+// MakeSubqueryTable returns a derived table that selects from the result
+// set of the query, with a field for each of its output columns. The table
+// is given its own alias when it is added to the enclosing query
+func MakeSubqueryTable(q *Query) *Table {
+	return &Table{
+		Name:     "subquery",
+		Fields:   q.outputFields(),
+		Subquery: q,
+	}
+}
+
+// MakeSubqueryJoinTable returns a derived join table that joins the result
+// set of the query, with a field for each of its output columns
+func MakeSubqueryJoinTable(q *Query, _type JoinType) *JoinTable {
+	return &JoinTable{
+		Table:    *MakeSubqueryTable(q),
+		JoinType: _type,
+	}
+}
+
+// subquerySQL returns the parenthesised SQL of the query, for use as an
+// operand of a predicate
+func (q *Query) subquerySQL(b *builder) (string, error) {
+	sql, err := q.sql(b)
+	if err != nil {
+		return "", fmt.Errorf("Subquery: %v", err)
+	}
+	return "(" + sql + ")", nil
+}
+
+// Existence is an EXISTS (or NOT EXISTS) predicate on the rows returned by a
+// subquery, which is typically correlated to the enclosing query by where
+// conditions that compare its fields to those of the enclosing query
+type Existence struct {
+	Query   *Query
+	Negated bool
+}
+
+// Exists returns a predicate that holds when the query returns any rows
+func Exists(q *Query) *Existence {
+	return &Existence{Query: q}
+}
+
+// NotExists returns a predicate that holds when the query returns no rows
+func NotExists(q *Query) *Existence {
+	return &Existence{Query: q, Negated: true}
+}
+
+// SQL returns the SQL representation of the predicate
+func (e *Existence) SQL() (string, error) {
+	return e.conditionSQL(inlineBuilder())
+}
+
+// SQLWithArgs returns the parameterized SQL representation of the predicate,
+// along with the values in placeholder order
+func (e *Existence) SQLWithArgs() (string, []interface{}, error) {
+	return conditionSQLWithArgs(e)
+}
+
+func (e *Existence) conditionSQL(b *builder) (string, error) {
+	if e == nil || e.Query == nil {
+		return "", fmt.Errorf("EXISTS predicate has no subquery")
+	}
+	subquery, err := e.Query.subquerySQL(b)
+	if err != nil {
+		return "", err
+	}
+	if e.Negated {
+		return "NOT EXISTS " + subquery, nil
+	}
+	return "EXISTS " + subquery, nil
+}
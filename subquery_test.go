@@ -0,0 +1,46 @@
+package strata
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComputedFieldsAreNamedByTheirOutputName(t *testing.T) {
+	ts := &Table{Name: "erf"}
+	ts.AddFields(
+		NumberField("township_id"),
+		AggregateField(NumberField("area"), Sum),
+		TableField{Name: "zoning", FormattedName: `upper("zoning")`, Type: String},
+		WindowField("", RowNumber, &Window{Name: "w"}),
+	)
+
+	q := &Query{}
+	q.SetBaseTable(ts)
+	*ts.Alias = "e"
+	q.AddGroupBy(ts.FieldByName("township_id"))
+
+	got := q.NestedFields()
+	want := `"e"."township_id", SUM("e"."area"), upper("e"."zoning"), ROW_NUMBER() OVER "w"`
+	if got != want {
+		t.Errorf("got %v, want the fields of a top level query to be left unnamed as %v", got, want)
+	}
+
+	names := []string{}
+	for _, field := range q.outputFields() {
+		names = append(names, field.Name)
+	}
+	if got, want := strings.Join(names, ", "), "township_id, area, zoning, row_number"; got != want {
+		t.Errorf("got output columns %v, want %v", got, want)
+	}
+
+	outer := &Query{}
+	outer.SetBaseTable(MakeSubqueryTable(q))
+	got, err := outer.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `SELECT "e"."township_id", SUM("e"."area") as "area", upper("e"."zoning") as "zoning", ROW_NUMBER() OVER "w" as "row_number" FROM "erf" "e"`
+	if !strings.Contains(got, want) {
+		t.Errorf("got %v, want the derived table to contain %v", got, want)
+	}
+}
@@ -1,6 +1,9 @@
 package strata
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // TableField is an abstraction of the field type
 type TableField struct {
@@ -9,6 +12,20 @@ type TableField struct {
 	FormattedName string    `json:"formattedName"` // unquoted provision for custom names (perhaps using formulas) - i.e. SUBSTRING(\"fieldName\" FROM '[A-Za-z]+_([A-Za-z]+[A-Z.])').
 	FriendlyName  string    `json:"friendlyName"`
 	Type          FieldType `json:"type"`
+	ElementType   string    `json:"elementType"` // Postgres type of the elements of array fields, i.e. integer
+	SRID          int       `json:"srid"`        // spatial reference of geometry fields, if known
+
+	Aggregate AggregateFunction `json:"aggregate"` // aggregate function that is applied to the field
+	Delimiter string            `json:"delimiter"` // delimiter used when the aggregate is StringAgg
+
+	WindowFunction WindowFunction `json:"windowFunction"` // window function that is applied to the field
+	WindowArgs     []interface{}  `json:"windowArgs"`     // arguments of the window function that follow the field
+	Over           *Window        `json:"over"`           // window that the window function or aggregate is computed over
+
+	Expression Expression      `json:"-"`      // expression that the field is computed as, in place of its column
+	Output     *GeometryOutput `json:"output"` // way in which a geometry field is selected
+
+	table *tableRef // table that the field belongs to, by which its alias is resolved
 }
 
 // TableFields is an array of table fields
@@ -21,34 +38,98 @@ func (tf *TableField) pickFriendlyName() string {
 	return ""
 }
 
-func (tf *TableField) transformsField() bool {
-	return containsQuoted(tf.FormattedName, tf.Name)
-}
-
-// fixFormattedName tries to detect if the selector name is in the Forma ttedName
-// field.
-func (tf *TableField) fixFormattedName() string {
-	n := tf.Name
-	if tf.Name == "" || !strings.Contains(tf.FormattedName, tf.Name) || !tf.transformsField() {
+// fixFormattedName qualifies every occurrence of the quoted name of the
+// field in the FormattedName with the alias of its table, leaving those that
+// are already qualified as they are. Expressions that refer to other fields
+// are better built with an Expression, of which the fields are qualified
+// structurally
+func (tf *TableField) fixFormattedName(alias string) string {
+	if tf.Name == "" || alias == "" {
 		return tf.FormattedName
 	}
 
-	createdField := delimitDot(*tf.Alias, tf.FormattedName)
-
-	return strings.Replace(tf.FormattedName, n, createdField, 1)
+	var (
+		quoted    = insertDoubleQuotes(tf.Name)
+		qualified = chainSelector(alias, tf.Name)
+		rest      = tf.FormattedName
+		sql       = ""
+	)
+	for {
+		i := strings.Index(rest, quoted)
+		if i == -1 {
+			return sql + rest
+		}
+		if i > 0 && rest[i-1] == '.' {
+			sql += rest[:i+len(quoted)]
+		} else {
+			sql += rest[:i] + qualified
+		}
+		rest = rest[i+len(quoted):]
+	}
 }
 
-func (tf *TableField) pickSelectorName() string {
+func (tf *TableField) pickSelectorName(alias string) string {
 	if tf.FormattedName != "" {
-		return tf.fixFormattedName()
+		return tf.fixFormattedName(alias)
 	}
 
-	if tf.Alias != nil && *tf.Alias != "" && tf.Name != "" {
-		return chainSelector(*tf.Alias, tf.Name)
+	if alias != "" && tf.Name != "" {
+		return chainSelector(alias, tf.Name)
 	}
 	return insertDoubleQuotes(tf.Name)
 }
 
+// selectorSQL returns the selector of the field with any aggregate or
+// window function applied to it. The selector is only rendered once per alias
+// that the field is qualified with, so that the field is written identically (using the same placeholders)
+// wherever it is referred to, such as in the GROUP BY clause
+func (tf *TableField) selectorSQL(b *builder) (string, error) {
+	key := selectorKey{field: tf, alias: b.fieldAlias(tf)}
+	if sql, ok := b.selectors[key]; ok {
+		return sql, nil
+	}
+
+	selector := tf.pickSelectorName(key.alias)
+	if tf.Expression != nil {
+		s, err := tf.Expression.expressionSQL(b)
+		if err != nil {
+			return "", err
+		}
+		selector = s
+	}
+
+	sql, err := tf.windowSQL(b, selector)
+	if err != nil {
+		return "", err
+	}
+
+	if b.selectors == nil {
+		b.selectors = map[selectorKey]string{}
+	}
+	b.selectors[key] = sql
+	return sql, nil
+}
+
+// outputName returns the name of the column that the field is returned as
+func (tf *TableField) outputName() string {
+	if tf.FriendlyName != "" {
+		return tf.FriendlyName
+	}
+	if tf.WindowFunction != NoWindowFunction {
+		return strings.ToLower(tf.WindowFunction.SQL())
+	}
+	if tf.Name == "" && tf.Aggregate != NoAggregate {
+		return strings.ToLower(tf.Aggregate.SQL())
+	}
+	return tf.Name
+}
+
+// isColumn returns whether the field refers to a column of its table as is,
+// rather than to an expression
+func (tf *TableField) isColumn() bool {
+	return tf.Name != "" && tf.FormattedName == "" && tf.Expression == nil && tf.Aggregate == NoAggregate && !tf.IsWindow()
+}
+
 func (tf *TableFields) append(field ...TableField) {
 	*tf = append(*tf, field...)
 }
@@ -84,25 +165,65 @@ func (tf *TableFields) getIndex(name string) int {
 	return -1
 }
 
-// SQL returns the SQL representation of the field
+// SQL returns the SQL representation of the field. It is best-effort: an
+// empty string is returned if the field can not be rendered, for which
+// SelectSQL returns the error instead
 func (tf *TableField) SQL() string {
-	if tf == nil {
+	sql, err := tf.SelectSQL()
+	if err != nil {
 		return ""
 	}
-	sql := tf.pickSelectorName()
+	return sql
+}
+
+// SelectSQL returns the SQL representation of the field as it is selected,
+// or the error that was encountered while rendering it
+func (tf *TableField) SelectSQL() (string, error) {
+	return tf.sql(inlineBuilder())
+}
+
+func (tf *TableField) sql(b *builder) (string, error) {
+	if tf == nil {
+		return "", nil
+	}
+	sql, err := tf.selectorSQL(b)
+	if err != nil {
+		return "", err
+	}
+	// The output mode only applies to the select list, so that conditions,
+	// groupings and orderings refer to the geometry itself
+	sql = tf.Output.apply(sql, tf.SRID)
 	if suffix := tf.pickFriendlyName(); suffix != "" {
 		sql += " as " + suffix
+	} else if name := tf.outputName(); name != "" && (tf.Output != nil || tf.Expression != nil || b.named && !tf.isColumn()) {
+		// Name the column by its output name rather than letting Postgres
+		// name it after the outermost function. Other computed fields are
+		// only named where the column is referred to by that name, i.e. by
+		// subquery tables, common table expressions, feature collections,
+		// tiles and the ordering of unions
+		sql += " as " + insertDoubleQuotes(name)
 	}
-	return sql
+	return sql, nil
 }
 
-// WhereClauseSQL returns the sql that would appear in a where clause
+// WhereClauseSQL returns the sql that would appear in a where clause. It is
+// best-effort: an empty string is returned if the field can not be rendered,
+// for which SelectorSQL returns the error instead
 func (tf *TableField) WhereClauseSQL() string {
-	if sn := tf.pickSelectorName(); sn != "" {
-		return sn
+	sql, err := tf.SelectorSQL()
+	if err != nil {
+		return ""
 	}
+	return sql
+}
 
-	return ""
+// SelectorSQL returns the sql that would appear in a where clause, or the
+// error that was encountered while rendering it
+func (tf *TableField) SelectorSQL() (string, error) {
+	if tf == nil {
+		return "", fmt.Errorf("TableField object is undefined")
+	}
+	return tf.selectorSQL(inlineBuilder())
 }
 
 // ToSlice is a convenient method for returning a slice from
@@ -131,6 +252,21 @@ func (tf *TableField) IsGeometry() bool {
 	return tf.Type == Geometry
 }
 
+// IsJSON returns whether the field type is a JSON field
+func (tf *TableField) IsJSON() bool {
+	return tf.Type == JSON
+}
+
+// IsArray returns whether the field type is an array field
+func (tf *TableField) IsArray() bool {
+	return tf.Type == Array
+}
+
+// IsLTree returns whether the field type is an ltree field
+func (tf *TableField) IsLTree() bool {
+	return tf.Type == LTree
+}
+
 func (tf *TableFields) addField(alias *string, name, friendlyName, formattedName string, _type FieldType) {
 	tf.append(makeField(alias, name, friendlyName, formattedName, _type))
 }
@@ -152,9 +288,22 @@ func field(name string, _type FieldType) TableField {
 	}
 }
 
-// Field returns a TableField of parsed type
+// Field returns a TableField of parsed type. Array types are given by the
+// type of their elements, i.e. integer[]
 func Field(name string, _type string) TableField {
-	return field(name, ParseFieldType(_type))
+	tf := field(name, ParseFieldType(_type))
+	if _type = strings.TrimSpace(_type); tf.Type == Array && strings.HasSuffix(_type, "[]") {
+		tf.ElementType = strings.TrimSuffix(_type, "[]")
+	}
+	return tf
+}
+
+// ArrayField returns a TableField of Array type, of which the elements are
+// of the given Postgres type, i.e. text
+func ArrayField(name string, elementType string) TableField {
+	tf := field(name, Array)
+	tf.ElementType = elementType
+	return tf
 }
 
 // StringField returns a TableField of string type
@@ -177,6 +326,16 @@ func GeometryField(name string) TableField {
 	return field(name, Geometry)
 }
 
+// JSONField returns a TableField of JSON type
+func JSONField(name string) TableField {
+	return field(name, JSON)
+}
+
+// LTreeField returns a TableField of LTree type
+func LTreeField(name string) TableField {
+	return field(name, LTree)
+}
+
 func (tf *TableFields) addStringField(alias *string, name, friendlyName, formattedName string) {
 	tf.addField(alias, name, friendlyName, formattedName, String)
 }
@@ -222,28 +381,80 @@ func (tf *TableFields) addGeometryFields(alias *string, fields ...string) {
 	}
 }
 
+// refersTo reports whether the fields refer to the same field of the same
+// table. Rather than by address, fields are compared by the table that they
+// belong to and by name, as the fields of a table are moved when fields are
+// added to it
+func (tf *TableField) refersTo(other *TableField) bool {
+	if tf == nil || other == nil || tf == other {
+		return tf == other
+	}
+	return tf.table != nil && tf.table == other.table && tf.Name == other.Name && tf.FriendlyName == other.FriendlyName
+}
+
+// detach returns a copy of the fields, in which the fields that belong to
+// the table with identity previous belong to the table with identity ref
+func (tf TableFields) detach(previous, ref *tableRef) TableFields {
+	if tf == nil {
+		return nil
+	}
+	fields := make(TableFields, len(tf))
+	for i, field := range tf {
+		if field.table == previous {
+			field.table = ref
+		}
+		fields[i] = field
+	}
+	return fields
+}
+
 func (tf *TableFields) setAlias(alias *string) {
 	for i := 0; tf != nil && i < len(*tf); i++ {
 		(*tf)[i].Alias = alias
 	}
 }
 
-// SQL returns the SQL representation of the table fields
+// SQL returns the SQL representation of the table fields. It is best-effort:
+// an empty string is returned if any of the fields can not be rendered, for
+// which SelectSQL returns the error instead
 func (tf *TableFields) SQL() string {
+	sql, err := tf.SelectSQL()
+	if err != nil {
+		return ""
+	}
+	return sql
+}
+
+// SelectSQL returns the SQL representation of the table fields, or the error
+// that was encountered while rendering them
+func (tf *TableFields) SelectSQL() (string, error) {
+	fields := []*TableField{}
+	for i := range *tf {
+		fields = append(fields, &(*tf)[i])
+	}
+	return fieldsSQL(inlineBuilder(), fields)
+}
+
+// fieldsSQL returns the comma separated SQL representation of the fields
+func fieldsSQL(b *builder, fields []*TableField) (string, error) {
 	sql := ""
-	for i, field := range *tf {
-		if i > 0 && i < len(*tf) {
+	for i, field := range fields {
+		if i > 0 {
 			sql += ", "
 		}
-		sql += field.SQL()
+		s, err := field.sql(b)
+		if err != nil {
+			return "", fmt.Errorf("Field %v: %v", field.outputName(), err)
+		}
+		sql += s
 	}
-
-	return sql
+	return sql, nil
 }
 
-// Where returns a where condition on the given field
+// Where returns a where condition on the given field. The RHS is ignored
+// by the comparison types that take none, such as IsNull
 func (tf *TableField) Where(comparisonType ComparisonType, rhs interface{}) *Wheres {
-	if tf == nil || rhs == nil {
+	if tf == nil || (rhs == nil && comparisonType.NeedsRHS()) {
 		return nil
 	}
 	return &Wheres{
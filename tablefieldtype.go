@@ -1,5 +1,7 @@
 package strata
 
+import "strings"
+
 // FieldType is the enumerated fieldtype
 type FieldType int
 
@@ -14,6 +16,12 @@ const (
 	Date
 	// Geometry is part of the enum for field types
 	Geometry
+	// LTree is part of the enum for field types
+	LTree
+	// JSON is part of the enum for field types
+	JSON
+	// Array is part of the enum for field types
+	Array
 )
 
 func (ft *FieldType) String() string {
@@ -29,6 +37,12 @@ func (ft *FieldType) String() string {
 		return "Date"
 	case Geometry:
 		return "Geometry"
+	case LTree:
+		return "LTree"
+	case JSON:
+		return "JSON"
+	case Array:
+		return "Array"
 	case Nil:
 		fallthrough
 	default:
@@ -48,7 +62,7 @@ func isNumber(name string) bool {
 
 func isString(name string) bool {
 	name = cleanString(name)
-	return name == "string" || name == "varchar" || name == "char"
+	return name == "string" || name == "varchar" || name == "char" || name == "text"
 }
 
 func isGeometry(name string) bool {
@@ -56,6 +70,21 @@ func isGeometry(name string) bool {
 	return name == "geo" || name == "geom" || name == "geometry"
 }
 
+func isLTree(name string) bool {
+	name = cleanString(name)
+	return name == "ltree"
+}
+
+func isJSON(name string) bool {
+	name = cleanString(name)
+	return name == "json" || name == "jsonb"
+}
+
+func isArray(name string) bool {
+	name = cleanString(name)
+	return name == "array" || strings.HasSuffix(name, "[]")
+}
+
 // ParseFieldType returns a FieldType
 func ParseFieldType(_type string) FieldType {
 	if isString(_type) {
@@ -74,5 +103,17 @@ func ParseFieldType(_type string) FieldType {
 		return Geometry
 	}
 
+	if isLTree(_type) {
+		return LTree
+	}
+
+	if isJSON(_type) {
+		return JSON
+	}
+
+	if isArray(_type) {
+		return Array
+	}
+
 	return Nil
 }
@@ -5,14 +5,35 @@ import (
 	"reflect"
 )
 
-// Table is an abstraction of the table type
+// Table is an abstraction of the table type. A table with a Subquery is a
+// derived table, and a table with a Function selects from the rows returned
+// by a set-returning function (such as unnest), which is evaluated laterally
+// so that it can refer to the fields of the preceding tables. In both cases
+// the Name is only used in the absence of an alias. Columns are the columns
+// of the table that can be referred to (i.e. in conditions, ON clauses and
+// orderings) without being selected, unlike Fields. Conditions is a tree of
+// And, Or and Not groups that the rows of the table are filtered by as well,
+// which is joined to the WhereConditions using the AND keyword
 type Table struct {
 	Name            string
 	Schema          string
 	Alias           *string
 	LHS             string
 	Fields          TableFields
+	Columns         TableFields
 	WhereConditions Wheres
+	Conditions      Condition
+	Subquery        *Query
+	Function        Expression
+
+	ref *tableRef // identity of the table, which its fields refer to
+}
+
+// tableRef is the identity of a table, by which the fields of the table are
+// resolved to the alias that the table is rendered with. It is not empty, so
+// that every allocated tableRef has a distinct address
+type tableRef struct {
+	_ byte
 }
 
 // Tables is a collection of table
@@ -29,8 +50,10 @@ func (t *Table) IsJoinTable() bool {
 
 // AddFields adds all the desired string fields
 func (t *Table) AddFields(fields ...TableField) {
+	ref := t.identity()
 	for i := 0; i < len(fields); i++ {
 		fields[i].Alias = t.Alias
+		fields[i].table = ref
 	}
 	t.Fields.append(fields...)
 }
@@ -43,57 +66,102 @@ func (t *Table) AddWhereCondition(lhs *TableField, rhs interface{}, comparisonTy
 
 // AddSimpleStringFields adds all the desired string fields
 func (t *Table) AddSimpleStringFields(fields ...string) {
-	t.Fields.addSimpleStringFields(t.Alias, fields...)
+	added := TableFields{}
+	added.addSimpleStringFields(nil, fields...)
+	t.AddFields(added...)
 }
 
 // AddSimpleNumberFields adds all the desired string fields
 func (t *Table) AddSimpleNumberFields(fields ...string) {
-	t.Fields.addSimpleNumberFields(t.Alias, fields...)
+	added := TableFields{}
+	added.addSimpleNumberFields(nil, fields...)
+	t.AddFields(added...)
 }
 
 // AddSimpleDateFields adds all the desired string fields
 func (t *Table) AddSimpleDateFields(fields ...string) {
-	t.Fields.addSimpleDateFields(t.Alias, fields...)
+	added := TableFields{}
+	added.addSimpleDateFields(nil, fields...)
+	t.AddFields(added...)
 }
 
 // AddGeometryField adds a single complex field type
 func (t *Table) AddGeometryField(name, friendlyName, formattedName string) {
-	t.Fields.addGeometryField(t.Alias, name, friendlyName, formattedName)
+	added := TableFields{}
+	added.addGeometryField(nil, name, friendlyName, formattedName)
+	t.AddFields(added...)
 }
 
 // AddStringField adds a single complex field type
 func (t *Table) AddStringField(name, friendlyName, formattedName string) {
-	t.Fields.addStringField(t.Alias, name, friendlyName, formattedName)
+	added := TableFields{}
+	added.addStringField(nil, name, friendlyName, formattedName)
+	t.AddFields(added...)
 }
 
 // AddEmptyField adds a single complex field type
 func (t *Table) AddEmptyField(name, friendlyName, formattedName string) {
-	t.Fields.addStringField(t.Alias, name, friendlyName, formattedName)
+	added := TableFields{}
+	added.addStringField(nil, name, friendlyName, formattedName)
+	t.AddFields(added...)
 }
 
 // AddNumberField adds a single complex field type
 func (t *Table) AddNumberField(name, friendlyName, formattedName string) {
-	t.Fields.addNumberField(t.Alias, name, friendlyName, formattedName)
+	added := TableFields{}
+	added.addNumberField(nil, name, friendlyName, formattedName)
+	t.AddFields(added...)
 }
 
 // AddDateField adds a single complex field type
 func (t *Table) AddDateField(name, friendlyName, formattedName string) {
-	t.Fields.addDateField(t.Alias, name, friendlyName, formattedName)
+	added := TableFields{}
+	added.addDateField(nil, name, friendlyName, formattedName)
+	t.AddFields(added...)
+}
+
+// AddAggregateField adds a field that applies the aggregate function to the
+// column with the given name
+func (t *Table) AddAggregateField(name, friendlyName string, aggregate AggregateFunction) {
+	added := TableFields{}
+	added.addAggregateField(nil, name, friendlyName, aggregate)
+	t.AddFields(added...)
 }
 
 // AddFieldByProperties adds a single field to the dataset
 func (t *Table) AddFieldByProperties(name, friendlyName, formattedName, _type string) {
-	t.Fields.addFieldByProperties(t.Alias, name, friendlyName, formattedName, _type)
+	added := TableFields{}
+	added.addFieldByProperties(nil, name, friendlyName, formattedName, _type)
+	t.AddFields(added...)
 }
 
-// FieldByName returns a field by the name
+// FieldByName returns a field by the name, which is either one of the
+// selected Fields or one of the Columns of the table
 func (t *Table) FieldByName(name string) *TableField {
-	return t.Fields.fieldByName(name)
+	if field := t.Fields.fieldByName(name); field != nil {
+		return field
+	}
+	return t.Columns.fieldByName(name)
+}
+
+// fieldsByName returns the fields with the given names, returning an error
+// if any of them can not be found
+func (t *Table) fieldsByName(names ...string) ([]*TableField, error) {
+	fields := make([]*TableField, 0, len(names))
+	for _, name := range names {
+		field := t.FieldByName(name)
+		if field == nil {
+			return nil, fmt.Errorf("Could not find field %v in the Table object", name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
 }
 
 // SetWhereConditions creates a where condition on the table object on an existing object in the object.
 // It first inspects if the field with the given name exists in the object, if not, returning an error
-// Then it attempts to create a where condition given the predicate and add it to the table object
+// Then it attempts to create a where condition given the predicate and add it to the table object.
+// Conditions that are more involved can be assigned to Conditions as a tree of And, Or and Not groups
 func (t *Table) SetWhereConditions(fieldName string, comparisonType ComparisonType, rhs interface{}) error {
 	where := t.FieldByName(fieldName).Where(comparisonType, rhs)
 	if where == nil {
@@ -104,8 +172,38 @@ func (t *Table) SetWhereConditions(fieldName string, comparisonType ComparisonTy
 	return nil
 }
 
-// SQL returns the name of the table object represented as an SQL selector
+// SQL returns the name of the table object represented as an SQL selector.
+// It is best-effort: an empty string is returned if the table can not be
+// rendered (i.e. its subquery or function is invalid), for which SourceSQL
+// returns the error instead
 func (t *Table) SQL() string {
+	sql, err := t.SourceSQL()
+	if err != nil {
+		return ""
+	}
+	return sql
+}
+
+// SourceSQL returns the name of the table object represented as an SQL
+// selector, or the error that was encountered while rendering it
+func (t *Table) SourceSQL() (string, error) {
+	return t.sql(inlineBuilder())
+}
+
+func (t *Table) sql(b *builder) (string, error) {
+	if t.Subquery != nil {
+		restore := b.nameOutputs()
+		subquery, err := t.Subquery.sql(b)
+		restore()
+		if err != nil {
+			return "", err
+		}
+		return delimitSpace("("+subquery+")", insertDoubleQuotes(t.scopedAliasOrName(b))), nil
+	}
+	if t.Function != nil {
+		return t.functionSQL(b)
+	}
+
 	sql := ""
 	if t.Schema != "" {
 		sql += chainSelector(t.Schema, t.Name)
@@ -113,17 +211,111 @@ func (t *Table) SQL() string {
 		sql += insertDoubleQuotes(t.Name)
 	}
 
+	if alias := b.tableAlias(t); alias != "" {
+		sql += " " + insertDoubleQuotes(alias)
+	}
+	return sql, nil
+}
+
+// functionSQL returns the set-returning function of the table, naming its
+// output columns by the names of the fields of the table
+func (t *Table) functionSQL(b *builder) (string, error) {
+	function, err := t.Function.expressionSQL(b)
+	if err != nil {
+		return "", err
+	}
+	columns := []string{}
+	for _, field := range t.Fields {
+		columns = append(columns, field.Name)
+	}
+	sql := delimitSpace("LATERAL", function, insertDoubleQuotes(t.scopedAliasOrName(b)))
+	if len(columns) > 0 {
+		sql += "(" + delimitQuoted(", ", columns...) + ")"
+	}
+	return sql, nil
+}
+
+// aliasOrName returns the alias of the table, or its name if it has none
+func (t *Table) aliasOrName() string {
 	if t.Alias != nil && *t.Alias != "" {
-		sql += " " + insertDoubleQuotes(*t.Alias)
+		return *t.Alias
+	}
+	return t.Name
+}
+
+// scopedAliasOrName returns the alias that the table is rendered with, or its
+// name if it has none
+func (t *Table) scopedAliasOrName(b *builder) string {
+	if alias := b.tableAlias(t); alias != "" {
+		return alias
+	}
+	return t.Name
+}
+
+// targetSQL returns the name of the table as the target of an INSERT, UPDATE
+// or DELETE statement, where the alias has to be introduced by AS
+func (t *Table) targetSQL(b *builder) string {
+	sql := ""
+	if t.Schema != "" {
+		sql += chainSelector(t.Schema, t.Name)
+	} else {
+		sql += insertDoubleQuotes(t.Name)
+	}
+
+	if alias := b.tableAlias(t); alias != "" {
+		sql += " AS " + insertDoubleQuotes(alias)
 	}
 	return sql
 }
 
+// tableConditions returns the where conditions of the base table and of each
+// of the join tables, joined using the OR keyword
+func tableConditions(base *Table, joinTables JoinTables) *ConditionGroup {
+	wheres := Or(base.conditions())
+	wheres.Append(joinTables.wheres()...)
+	return wheres
+}
+
+// setRandomAlias assigns a new random alias to the table and its fields, by
+// which they are referred to within a statement. The alias is allocated
+// anew, so that it is not shared with any copy of the table
+func (t *Table) setRandomAlias() {
+	alias := randomString(4)
+	t.Alias = &alias
+	t.identity()
+	t.Fields.setAlias(t.Alias)
+	t.Columns.setAlias(t.Alias)
+}
+
+// identity returns the identity of the table, allocating it on first use and
+// assigning it to any of the fields of the table that do not belong to a
+// table yet. As the fields refer to the identity rather than to the address
+// of the table, the fields that were referred to before the fields of the
+// table were moved (i.e. by AddFields) still resolve to the table
+func (t *Table) identity() *tableRef {
+	if t.ref == nil {
+		t.ref = &tableRef{}
+	}
+	for _, fields := range []TableFields{t.Fields, t.Columns} {
+		for i := range fields {
+			if fields[i].table == nil {
+				fields[i].table = t.ref
+			}
+		}
+	}
+	return t.ref
+}
+
+// conditions returns the WhereConditions and the Conditions of the table,
+// joined using the AND keyword
+func (t *Table) conditions() Condition {
+	return And(t.WhereConditions, t.Conditions)
+}
+
 func (t *Table) fixFields() {
 	if len(t.Fields) == 0 {
 		t.Fields = nil
 	}
-
 	if len(t.WhereConditions.ToNativeSlice()) == 0 {
 		t.WhereConditions = Wheres{}
 	}
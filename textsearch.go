@@ -0,0 +1,170 @@
+package strata
+
+import "fmt"
+
+// TextSearchQueryType is the function that parses the text of a full-text
+// search into a tsquery
+type TextSearchQueryType int
+
+const (
+	// PlainQuery matches all of the words of the text
+	PlainQuery TextSearchQueryType = iota
+	// WebSearchQuery parses the text like a web search engine, supporting
+	// "quoted phrases", OR and -negation
+	WebSearchQuery
+	// PhraseQuery matches the words of the text in sequence
+	PhraseQuery
+	// RawQuery parses the text as tsquery syntax, i.e. fat & (rat | cat)
+	RawQuery
+)
+
+// SQL returns the name of the function that parses the query
+func (qt *TextSearchQueryType) SQL() string {
+	if qt == nil {
+		return ""
+	}
+	switch *qt {
+	case WebSearchQuery:
+		return "websearch_to_tsquery"
+	case PhraseQuery:
+		return "phraseto_tsquery"
+	case RawQuery:
+		return "to_tsquery"
+	case PlainQuery:
+		fallthrough
+	default:
+		return "plainto_tsquery"
+	}
+}
+
+// TextSearch is a full-text search query, which is the RHS of the
+// TextSearchMatches comparison. Config is the text search configuration
+// (i.e. english), which defaults to that of the database
+type TextSearch struct {
+	Text   string
+	Type   TextSearchQueryType
+	Config string
+}
+
+// configArgs returns the configuration argument of the text search
+// functions, if any
+func configArgs(config string) []string {
+	if config == "" {
+		return nil
+	}
+	return []string{insertSingleQuotes(config)}
+}
+
+// querySQL returns the tsquery of the text search
+func (ts *TextSearch) querySQL(b *builder) (string, error) {
+	text, err := b.bind(ts.Text)
+	if err != nil {
+		return "", err
+	}
+	return ts.Type.SQL() + "(" + delimit(", ", append(configArgs(ts.Config), text)...) + ")", nil
+}
+
+// textSearchSQL returns the tsquery of a value on the RHS of a full-text
+// search, where strings are searched for as plain text
+func textSearchSQL(b *builder, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case TextSearch:
+		return v.querySQL(b)
+	case *TextSearch:
+		return v.querySQL(b)
+	case string:
+		return (&TextSearch{Text: v}).querySQL(b)
+	case *TableField, Raw:
+		return b.operandSQL(v)
+	default:
+		return "", fmt.Errorf("Unsupported text search of Go type %T", value)
+	}
+}
+
+// documentExpression is the tsvector of the text of one or more fields,
+// which are concatenated with spaces and with nulls treated as empty text
+type documentExpression struct {
+	config string
+	fields []*TableField
+}
+
+func (de documentExpression) expressionSQL(b *builder) (string, error) {
+	if len(de.fields) == 0 {
+		return "", fmt.Errorf("Text search document has no fields")
+	}
+	texts := make([]string, len(de.fields))
+	for i, field := range de.fields {
+		s, err := field.selectorSQL(b)
+		if err != nil {
+			return "", err
+		}
+		texts[i] = "coalesce(" + s + "::text, '')"
+	}
+	return "to_tsvector(" + delimit(", ", append(configArgs(de.config), delimit(" || ' ' || ", texts...))...) + ")", nil
+}
+
+// TSVector returns a field that is the tsvector of the text of the given
+// fields, parsed using the text search configuration (if defined). It is
+// compared to text searches using TextSearchMatches
+func TSVector(config string, fields ...*TableField) TableField {
+	return expressionField("document", Nil, documentExpression{config: config, fields: fields})
+}
+
+// rankExpression is the rank of a tsvector for a text search
+type rankExpression struct {
+	document *TableField
+	search   TextSearch
+}
+
+func (re rankExpression) expressionSQL(b *builder) (string, error) {
+	document, err := re.document.selectorSQL(b)
+	if err != nil {
+		return "", err
+	}
+	query, err := re.search.querySQL(b)
+	if err != nil {
+		return "", err
+	}
+	return "ts_rank(" + document + ", " + query + ")", nil
+}
+
+// TSRank returns a field that ranks the tsvector field (i.e. one returned by
+// TSVector) for the text search, which can be ordered on
+func TSRank(document *TableField, search TextSearch) TableField {
+	return expressionField("rank", Number, rankExpression{document: document, search: search})
+}
+
+// headlineExpression is the text of a field with the matches of a text
+// search highlighted
+type headlineExpression struct {
+	text    *TableField
+	search  TextSearch
+	options string
+}
+
+func (he headlineExpression) expressionSQL(b *builder) (string, error) {
+	text, err := he.text.selectorSQL(b)
+	if err != nil {
+		return "", err
+	}
+	query, err := he.search.querySQL(b)
+	if err != nil {
+		return "", err
+	}
+	args := append(configArgs(he.search.Config), text, query)
+	if he.options != "" {
+		options, err := b.bind(he.options)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, options)
+	}
+	return "ts_headline(" + delimit(", ", args...) + ")", nil
+}
+
+// TSHeadline returns a field that is the text of the given field with the
+// matches of the text search highlighted. The options are those of
+// ts_headline, i.e. "StartSel=<b>, StopSel=</b>, MaxFragments=2"
+func TSHeadline(text *TableField, search TextSearch, options string) TableField {
+	return expressionField("headline", String, headlineExpression{text: text, search: search, options: options})
+}
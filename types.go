@@ -1,7 +1,6 @@
 package strata
 
 import (
-	"bytes"
 	"fmt"
 	"strconv"
 )
@@ -14,42 +13,157 @@ type SQLElement interface {
 // Query is the second highest level of abstraction for the SQL result set -
 // they are joined together using unions
 type Query struct {
+	With       []*CommonTableExpression
 	baseTable  *Table
 	joinTables JoinTables
+	Grouping   []*TableField
+	Having     Condition
+	windows    []namedWindow
+	Ordering   OrderBys
 	Limit      int
+	Offset     int
+	// Keyset holds the values of the ORDER BY fields of the last row of the
+	// previous page - see SeekAfter
+	Keyset []interface{}
+	// FeatureCollection makes the query return its rows as a single GeoJSON
+	// feature collection - see AsFeatureCollection
+	FeatureCollection *FeatureCollection
+	// Tile makes the query return its rows as a Mapbox vector tile - see
+	// AsTile
+	Tile *Tile
 }
 
 // NestedFields returns all the that are in the query object (i.e.
 // in the base table and the join tables) as a single set of
-// TableFields. This is used to create the select statement
+// TableFields. This is used to create the select statement. It is
+// best-effort: an empty string is returned if any of the fields can not be
+// rendered, for which NestedFieldsSQL returns the error instead
 func (q *Query) NestedFields() string {
+	sql, err := q.NestedFieldsSQL()
+	if err != nil {
+		return ""
+	}
+	return sql
+}
+
+// NestedFieldsSQL returns the fields that are selected by the query like
+// NestedFields, or the error that was encountered while rendering them
+func (q *Query) NestedFieldsSQL() (string, error) {
+	b := inlineBuilder()
+	defer b.enterScope(q.tables()...)()
+	return fieldsSQL(b, q.selectedFields())
+}
+
+// tables returns the base table and the join tables of the query
+func (q *Query) tables() []*Table {
+	tables := []*Table{q.baseTable}
+	for i := range q.joinTables {
+		tables = append(tables, &q.joinTables[i].Table)
+	}
+	return tables
+}
+
+// outputFields returns a field for each of the columns in the result set of
+// the query, named by the friendly name of the selected field (or its name)
+func (q *Query) outputFields() TableFields {
 	fields := TableFields{}
-	fields.append(q.baseTable.Fields...)
-	fields.append(q.joinTables.fields()...)
-	return fields.SQL()
+	for _, field := range q.selectedFields() {
+		fields.addField(nil, field.outputName(), "", "", field.outputType())
+	}
+	return fields
+}
+
+// selectedFields returns the fields of the base table and of the join tables
+func (q *Query) selectedFields() []*TableField {
+	fields := []*TableField{}
+	for i := range q.baseTable.Fields {
+		fields = append(fields, &q.baseTable.Fields[i])
+	}
+	return append(fields, q.joinTables.fields()...)
 }
 
-// NestedWheres returns the nested where information
+// NestedGrouping returns the GROUP BY clause of the query, without the
+// keywords. When no grouping has been added explicitly and some of the
+// selected fields are aggregated, the query is grouped by all of the
+// selected fields that are not aggregated. It is best-effort: an empty
+// string is returned if the grouping can not be rendered, for which
+// NestedGroupingSQL returns the error instead
+func (q *Query) NestedGrouping() string {
+	sql, err := q.NestedGroupingSQL()
+	if err != nil {
+		return ""
+	}
+	return sql
+}
+
+// NestedGroupingSQL returns the GROUP BY clause of the query like
+// NestedGrouping, or the error that was encountered while rendering it
+func (q *Query) NestedGroupingSQL() (string, error) {
+	b := inlineBuilder()
+	defer b.enterScope(q.tables()...)()
+	return q.nestedGrouping(b)
+}
+
+func (q *Query) nestedGrouping(b *builder) (string, error) {
+	grouping := q.Grouping
+	if len(grouping) == 0 {
+		grouping = groupingFields(q.selectedFields())
+	}
+
+	sql := ""
+	for i, field := range grouping {
+		if i > 0 {
+			sql += ", "
+		}
+		s, err := field.selectorSQL(b)
+		if err != nil {
+			return "", err
+		}
+		sql += s
+	}
+	return sql, nil
+}
+
+// NestedWheres returns the nested where information. The conditions of
+// the base table and of each join table are joined using the OR keyword,
+// each being parenthesised where necessary. The keyset predicate, if any,
+// is joined to them using the AND keyword
 func (q *Query) NestedWheres() (string, error) {
-	wheres := WhereSet{}
+	return q.nestedWheres(inlineBuilder())
+}
+
+// nestedWheres returns the nested where information, with any extra
+// conditions joined to it using the AND keyword
+func (q *Query) nestedWheres(b *builder, extra ...Condition) (string, error) {
 	q.baseTable.fixFields()
 	q.joinTables.fixFields()
-	wheres.append(q.baseTable.WhereConditions)
-	wheres.append(q.joinTables.wheres()...)
-	return wheres.SQL()
+	wheres := tableConditions(q.baseTable, q.joinTables)
+
+	keyset, err := q.keysetCondition(b)
+	if err != nil {
+		return "", err
+	}
+	defer b.filterRows(true)()
+	return And(append([]Condition{wheres, keyset}, extra...)...).conditionSQL(b)
 }
 
 // NestedTables definition
 func (q *Query) NestedTables() (string, error) {
+	return q.nestedTables(inlineBuilder())
+}
+
+func (q *Query) nestedTables(b *builder) (string, error) {
 	var (
 		tables = ""
 		jt     = ""
 		err    error
 	)
 	q.baseTable.fixFields()
-	tables += q.baseTable.SQL()
+	if tables, err = q.baseTable.sql(b); err != nil {
+		return "", err
+	}
 
-	if jt, err = q.joinTables.SQL(); err != nil {
+	if jt, err = q.joinTables.sql(b); err != nil {
 		return "", err
 	}
 
@@ -60,29 +174,133 @@ func (q *Query) NestedTables() (string, error) {
 // SQL returns the sql representation of the Query hierarchy of
 // objects
 func (q *Query) SQL() (string, error) {
-	var buf bytes.Buffer
-	buf.Grow(300)
+	return q.sql(inlineBuilder())
+}
+
+// SQLWithArgs returns the sql representation of the Query hierarchy of
+// objects with every value replaced by a positional placeholder ($1..$n),
+// along with the values in placeholder order. The result can be handed
+// directly to database/sql or pgx
+func (q *Query) SQLWithArgs() (string, []interface{}, error) {
+	return sqlWithArgs(q)
+}
+
+func (q *Query) sql(b *builder) (string, error) {
 	if q == nil {
 		return "", fmt.Errorf("Query object is undefined - cannot create a union")
 	}
+	if q.baseTable == nil {
+		return "", fmt.Errorf("Query object has no base table")
+	}
+	switch {
+	case q.Tile != nil:
+		return q.tileSQL(b)
+	case q.FeatureCollection != nil:
+		return q.featureCollectionSQL(b)
+	default:
+		return q.selectSQL(b, nil)
+	}
+}
+
+// selectOverrides are the parts of the SELECT statement of a query that are
+// replaced when the query is wrapped by one of its modes. fields renders the
+// select list, condition is joined to the WHERE clause using the AND
+// keyword, and limit replaces the LIMIT of the query when it is lower
+type selectOverrides struct {
+	fields    func(b *builder) (string, error)
+	condition Condition
+	limit     int
+}
+
+// selectSQL returns the SELECT statement of the query, with the given parts
+// of it replaced (if any)
+func (q *Query) selectSQL(b *builder, o *selectOverrides) (string, error) {
+	if o == nil {
+		o = &selectOverrides{}
+	}
+	defer b.enterScope(q.tables()...)()
+	// A subquery within a WHERE clause only filters rows in its own WHERE clause
+	defer b.filterRows(false)()
+
+	with, err := withSQL(b, q.With)
+	if err != nil {
+		return "", err
+	}
+
+	var nf string
+	if o.fields != nil {
+		nf, err = o.fields(b)
+	} else {
+		nf, err = fieldsSQL(b, q.selectedFields())
+	}
+	if err != nil {
+		return "", err
+	}
 
 	var (
-		nf         = q.NestedFields()
 		sql        = delimitSpace("SELECT", nf)
-		tables, e1 = q.NestedTables()
-		where, e2  = q.NestedWheres()
+		tables, e1 = q.nestedTables(b)
+		where, e2  = q.nestedWheres(b, o.condition)
 	)
 	if e2 != nil || e1 != nil {
 		return "", fmt.Errorf("The following errors were encountered:\n(1)\tTABLES:\t%v\n(2)\tWHERE:\t%v", e1, e2)
 	}
 
+	if with != "" {
+		sql = delimitSpace(with, sql)
+	}
+
 	sql = delimitSpace(sql, "FROM", tables)
 	if where != "" {
 		sql = delimitSpace(sql, "WHERE", where)
 	}
 
-	if q.Limit != 0 {
-		sql = delimitSpace(sql, "LIMIT", strconv.Itoa(q.Limit))
+	groupBy, err := q.nestedGrouping(b)
+	if err != nil {
+		return "", err
+	}
+	if groupBy != "" {
+		sql = delimitSpace(sql, "GROUP BY", groupBy)
+	}
+
+	if q.Having != nil {
+		restore := b.filterRows(false)
+		having, err := q.Having.conditionSQL(b)
+		restore()
+		if err != nil {
+			return "", fmt.Errorf("HAVING: %v", err)
+		}
+		if having != "" {
+			sql = delimitSpace(sql, "HAVING", having)
+		}
+	}
+
+	windows, err := q.windowsSQL(b)
+	if err != nil {
+		return "", err
+	}
+	if windows != "" {
+		sql = delimitSpace(sql, "WINDOW", windows)
+	}
+
+	if len(q.Ordering) > 0 {
+		orderBy, err := q.Ordering.sql(b, false)
+		if err != nil {
+			return "", err
+		}
+		sql = delimitSpace(sql, "ORDER BY", orderBy)
+	}
+
+	limit := q.Limit
+	if o.limit != 0 && (limit == 0 || o.limit < limit) {
+		limit = o.limit
+	}
+	if limit != 0 {
+		sql = delimitSpace(sql, "LIMIT", strconv.Itoa(limit))
+	}
+
+	if q.Offset != 0 {
+		sql = delimitSpace(sql, "OFFSET", strconv.Itoa(q.Offset))
 	}
 
 	return sql, nil
@@ -90,9 +308,7 @@ func (q *Query) SQL() (string, error) {
 
 // SetBaseTable definition
 func (q *Query) SetBaseTable(bt *Table) {
-	one := randomString(4)
-	bt.Alias = &one
-	bt.Fields.setAlias(&one)
+	bt.setRandomAlias()
 	q.baseTable = bt
 }
 
@@ -107,19 +323,91 @@ func (q *Query) SetBaseTableFromProperties(name, schema string) {
 // AddJoinTables appends JoinTables into the Query object
 func (q *Query) AddJoinTables(tables ...JoinTable) {
 	for _, table := range tables {
-		one := randomString(4)
-		table.Alias = &one
-		table.Fields.setAlias(&one)
-		q.joinTables.append(table)
+		q.joinTables.add(q.baseTable, table)
+	}
+}
+
+// AddOrderBy appends orderings to the ORDER BY clause of the Query
+func (q *Query) AddOrderBy(orderings ...OrderBy) {
+	q.Ordering.append(orderings...)
+}
+
+// OrderByField appends an ordering on the field with the given name in the
+// given table (either the base table or one of the join tables) to the
+// ORDER BY clause of the Query
+func (q *Query) OrderByField(table *Table, name string, direction SortDirection) error {
+	field := table.FieldByName(name)
+	if field == nil {
+		return fmt.Errorf("Could not find field %v in the Table object", name)
 	}
+	q.AddOrderBy(OrderByField(field, direction))
+	return nil
+}
+
+// AddWith appends common table expressions to the WITH clause of the Query
+func (q *Query) AddWith(ctes ...*CommonTableExpression) {
+	q.With = append(q.With, ctes...)
+}
+
+// AddGroupBy appends fields to the GROUP BY clause of the Query, which
+// replaces the automatic grouping
+func (q *Query) AddGroupBy(fields ...*TableField) {
+	q.Grouping = append(q.Grouping, fields...)
+}
+
+// GroupByField appends the field with the given name in the given table to
+// the GROUP BY clause of the Query
+func (q *Query) GroupByField(table *Table, name string) error {
+	field := table.FieldByName(name)
+	if field == nil {
+		return fmt.Errorf("Could not find field %v in the Table object", name)
+	}
+	q.AddGroupBy(field)
+	return nil
+}
+
+// SetHaving sets the HAVING condition of the Query. Where conditions on
+// aggregated fields compare the aggregate expression
+func (q *Query) SetHaving(condition Condition) {
+	q.Having = condition
 }
 
 // Union is an abstraction of multiple queries
 type Union []Query
 
+// MakeUnion returns a union of the given queries
+func MakeUnion(queries ...Query) *Union {
+	u := Union(queries)
+	return &u
+}
+
+// Append appends queries to the union
+func (u *Union) Append(queries ...Query) {
+	*u = append(*u, queries...)
+}
+
+// OrderedBy returns the union with the given orderings applied to its
+// combined result set
+func (u *Union) OrderedBy(orderings ...OrderBy) *OrderedUnion {
+	ou := &OrderedUnion{Union: *u}
+	ou.AddOrderBy(orderings...)
+	return ou
+}
+
 // SQL returns the SQL for the given ResultSet, which
 // is an object that is composed of tables, fields and other types
 func (u *Union) SQL() (string, error) {
+	return u.sql(inlineBuilder())
+}
+
+// SQLWithArgs returns the parameterized SQL for the given ResultSet, along
+// with the values in placeholder order. Placeholders are numbered across
+// all of the queries in the union
+func (u *Union) SQLWithArgs() (string, []interface{}, error) {
+	return sqlWithArgs(u)
+}
+
+func (u *Union) sql(b *builder) (string, error) {
 	if u == nil {
 		return "", fmt.Errorf("Union object is undefined - cannot create a union")
 	}
@@ -129,7 +417,7 @@ func (u *Union) SQL() (string, error) {
 		if i > 0 {
 			sql += " UNION ALL "
 		}
-		q, err := query.SQL()
+		q, err := query.sql(b)
 		if err != nil {
 			return "", err
 		}
@@ -137,3 +425,47 @@ func (u *Union) SQL() (string, error) {
 	}
 	return sql, nil
 }
+
+// OrderedUnion is a union of which the combined result set is ordered. The
+// orderings can only refer to the output columns of the queries
+type OrderedUnion struct {
+	Union
+	Ordering OrderBys
+}
+
+// AddOrderBy appends orderings to the outer ORDER BY clause of the union
+func (ou *OrderedUnion) AddOrderBy(orderings ...OrderBy) {
+	ou.Ordering.append(orderings...)
+}
+
+// SQL returns the SQL of the union, followed by its ORDER BY clause
+func (ou *OrderedUnion) SQL() (string, error) {
+	return ou.sql(inlineBuilder())
+}
+
+// SQLWithArgs returns the parameterized SQL of the union and its ORDER BY
+// clause, along with the values in placeholder order
+func (ou *OrderedUnion) SQLWithArgs() (string, []interface{}, error) {
+	return sqlWithArgs(ou)
+}
+
+func (ou *OrderedUnion) sql(b *builder) (string, error) {
+	if ou == nil {
+		return "", fmt.Errorf("Union object is undefined - cannot create a union")
+	}
+
+	restore := b.nameOutputs()
+	sql, err := ou.Union.sql(b)
+	restore()
+	if err != nil {
+		return "", err
+	}
+	if len(ou.Ordering) > 0 {
+		orderBy, err := ou.Ordering.sql(b, true)
+		if err != nil {
+			return "", err
+		}
+		sql = delimitSpace(sql, "ORDER BY", orderBy)
+	}
+	return sql, nil
+}
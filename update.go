@@ -0,0 +1,87 @@
+package strata
+
+import "fmt"
+
+// Update is an abstraction of an UPDATE statement. The rows that are updated
+// are those that match the conditions of the table and those of each of the
+// join tables
+type Update struct {
+	mutation
+	assignments []assignment
+}
+
+// assignment is a single column assignment of the SET clause
+type assignment struct {
+	column *TableField
+	value  interface{}
+}
+
+// MakeUpdate returns an update statement on the given table
+func MakeUpdate(table *Table) *Update {
+	return &Update{mutation: makeMutation(table)}
+}
+
+// Set assigns a value to the field of the table with the given name. The
+// value can be a literal (which is bound when parameterized), another
+// *TableField or a Raw expression
+func (upd *Update) Set(name string, value interface{}) error {
+	column := upd.table.FieldByName(name)
+	if column == nil {
+		return fmt.Errorf("Could not find field %v in the Table object", name)
+	}
+	upd.assignments = append(upd.assignments, assignment{column: column, value: value})
+	return nil
+}
+
+// assignmentsSQL returns the comma separated assignments of a SET clause
+func assignmentsSQL(b *builder, assignments []assignment) (string, error) {
+	sql := ""
+	for i, a := range assignments {
+		if i > 0 {
+			sql += ", "
+		}
+		value, err := b.operandSQL(a.value)
+		if err != nil {
+			return "", fmt.Errorf("Assignment of %v: %v", a.column.Name, err)
+		}
+		sql += delimitSpace(insertDoubleQuotes(a.column.Name), "=", value)
+	}
+	return sql, nil
+}
+
+// SQL returns the SQL representation of the update statement
+func (upd *Update) SQL() (string, error) {
+	return upd.sql(inlineBuilder())
+}
+
+// SQLWithArgs returns the SQL representation of the update statement with
+// every value replaced by a positional placeholder, along with the values in
+// placeholder order
+func (upd *Update) SQLWithArgs() (string, []interface{}, error) {
+	return sqlWithArgs(upd)
+}
+
+func (upd *Update) sql(b *builder) (string, error) {
+	if upd == nil || upd.table == nil {
+		return "", fmt.Errorf("Update object is undefined")
+	}
+	defer b.enterScope(upd.tables()...)()
+	if len(upd.assignments) == 0 {
+		return "", fmt.Errorf("Update of %v has no assignments", upd.table.SQL())
+	}
+
+	set, err := assignmentsSQL(b, upd.assignments)
+	if err != nil {
+		return "", err
+	}
+	sql := delimitSpace("UPDATE", upd.table.targetSQL(b), "SET", set)
+
+	from, err := upd.fromSQL(b)
+	if err != nil {
+		return "", err
+	}
+	if from != "" {
+		sql = delimitSpace(sql, "FROM", from)
+	}
+	return upd.tailSQL(b, sql, "update")
+}
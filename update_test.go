@@ -0,0 +1,70 @@
+package strata
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUpdateRequiresTheConditionsOfEveryTable(t *testing.T) {
+	erf := &Table{Name: "erf"}
+	erf.AddFields(NumberField("township_id"), StringField("zoning"))
+	erf.WhereConditions = *erf.FieldByName("zoning").Where(Equal, "R1")
+
+	upd := MakeUpdate(erf)
+	if err := upd.Set("zoning", "R2"); err != nil {
+		t.Fatal(err)
+	}
+	township := JoinTable{Table: Table{Name: "township"}, JoinType: InnerJoin}
+	township.AddFields(NumberField("_id"), StringField("name"))
+	township.WhereConditions = *township.FieldByName("name").Where(Equal, "north")
+	township.LHSField, township.RHSField = township.FieldByName("_id"), erf.FieldByName("township_id")
+	township.ComparisonType = Equal
+	upd.AddJoinTables(township)
+	*erf.Alias, *upd.joinTables[0].Alias = "e", "t"
+
+	got, err := upd.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `WHERE "t"."_id" = "e"."township_id" AND "e"."zoning" = 'R1' AND "t"."name" = 'north'`
+	if !strings.Contains(got, want) {
+		t.Errorf("got %v, want it to contain %v", got, want)
+	}
+}
+
+func TestUpdateWherePrecedence(t *testing.T) {
+	ts := &Table{Name: "township", Schema: "cadastral"}
+	ts.AddFields(NumberField("_id"), StringField("name"))
+	name := ts.FieldByName("name")
+	ts.WhereConditions = Wheres{Wheres: []Where{
+		{LHSField: name, RHSField: "north", ComparisonType: Equal},
+		{LHSField: name, RHSField: "south", ComparisonType: Equal},
+	}}
+
+	upd := MakeUpdate(ts)
+	*ts.Alias = "t"
+	if err := upd.Set("name", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+
+	erf := JoinTable{Table: Table{Name: "erf"}, JoinType: InnerJoin}
+	erf.AddFields(NumberField("township_id"))
+	erf.LHSField, erf.RHSField = ts.FieldByName("_id"), erf.FieldByName("township_id")
+	erf.ComparisonType = Equal
+	upd.AddJoinTables(erf)
+	*upd.joinTables[0].Alias = "e"
+
+	got, err := upd.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `WHERE "t"."_id" = "e"."township_id" AND ("t"."name" = 'north' OR "t"."name" = 'south')`
+	if !strings.Contains(got, want) {
+		t.Errorf("got %v, want it to contain %v", got, want)
+	}
+
+	upd.joinTables[0].JoinType = LeftJoin
+	if _, err := upd.SQL(); err == nil {
+		t.Errorf("expected an error for a LEFT join table")
+	}
+}
@@ -0,0 +1,133 @@
+package strata
+
+import "fmt"
+
+// excludedAlias is the name by which the row proposed for insertion is
+// referred to in the ON CONFLICT clause
+var excludedAlias = "excluded"
+
+// Conflict is the ON CONFLICT clause of an insert statement. The conflict is
+// resolved by doing nothing, unless assignments are made using Set or
+// SetExcluded, in which case the conflicting row is updated. Where limits
+// the conflicting rows that are updated
+type Conflict struct {
+	table       *Table
+	target      []*TableField
+	constraint  string
+	assignments []assignment
+	Where       Condition
+}
+
+// Excluded returns a reference to the given field of the row that was
+// proposed for insertion, i.e. EXCLUDED."name", for use in the ON CONFLICT
+// clause
+func Excluded(field *TableField) *TableField {
+	if field == nil {
+		return nil
+	}
+	excluded := *field
+	excluded.Alias = &excludedAlias
+	excluded.table = nil
+	return &excluded
+}
+
+// OnConflict adds an ON CONFLICT clause targeting the unique index on the
+// fields of the table with the given names
+func (ins *Insert) OnConflict(names ...string) (*Conflict, error) {
+	target, err := ins.table.fieldsByName(names...)
+	if err != nil {
+		return nil, err
+	}
+	ins.conflict = &Conflict{table: ins.table, target: target}
+	return ins.conflict, nil
+}
+
+// OnConflictConstraint adds an ON CONFLICT clause targeting the constraint
+// with the given name
+func (ins *Insert) OnConflictConstraint(name string) *Conflict {
+	ins.conflict = &Conflict{table: ins.table, constraint: name}
+	return ins.conflict
+}
+
+// DoNothing resolves the conflict by skipping the row, discarding any of the
+// assignments that have been made
+func (c *Conflict) DoNothing() *Conflict {
+	c.assignments = nil
+	c.Where = nil
+	return c
+}
+
+// Set assigns a value to the field of the table with the given name when
+// the conflicting row is updated. The value can be a literal, another
+// *TableField (such as one returned by Excluded) or a Raw expression
+func (c *Conflict) Set(name string, value interface{}) error {
+	column := c.table.FieldByName(name)
+	if column == nil {
+		return fmt.Errorf("Could not find field %v in the Table object", name)
+	}
+	c.assignments = append(c.assignments, assignment{column: column, value: value})
+	return nil
+}
+
+// SetExcluded assigns the values of the row proposed for insertion to the
+// fields of the table with the given names when the conflicting row is
+// updated, i.e. "name" = EXCLUDED."name"
+func (c *Conflict) SetExcluded(names ...string) error {
+	for _, name := range names {
+		column := c.table.FieldByName(name)
+		if column == nil {
+			return fmt.Errorf("Could not find field %v in the Table object", name)
+		}
+		c.assignments = append(c.assignments, assignment{column: column, value: Excluded(column)})
+	}
+	return nil
+}
+
+// SetWhere limits the conflicting rows that are updated
+func (c *Conflict) SetWhere(condition Condition) *Conflict {
+	c.Where = condition
+	return c
+}
+
+func (c *Conflict) targetSQL() string {
+	if c.constraint != "" {
+		return "ON CONSTRAINT " + insertDoubleQuotes(c.constraint)
+	}
+	if len(c.target) > 0 {
+		return "(" + columnNamesSQL(c.target) + ")"
+	}
+	return ""
+}
+
+func (c *Conflict) sql(b *builder) (string, error) {
+	target := c.targetSQL()
+	if len(c.assignments) == 0 {
+		if c.Where != nil {
+			return "", fmt.Errorf("ON CONFLICT DO NOTHING can not have a WHERE condition")
+		}
+		if target == "" {
+			return "ON CONFLICT DO NOTHING", nil
+		}
+		return delimitSpace("ON CONFLICT", target, "DO NOTHING"), nil
+	}
+	if target == "" {
+		return "", fmt.Errorf("ON CONFLICT DO UPDATE requires a conflict target")
+	}
+
+	set, err := assignmentsSQL(b, c.assignments)
+	if err != nil {
+		return "", err
+	}
+	sql := delimitSpace("ON CONFLICT", target, "DO UPDATE SET", set)
+
+	if c.Where != nil {
+		where, err := c.Where.conditionSQL(b)
+		if err != nil {
+			return "", fmt.Errorf("ON CONFLICT WHERE: %v", err)
+		}
+		if where != "" {
+			sql = delimitSpace(sql, "WHERE", where)
+		}
+	}
+	return sql, nil
+}
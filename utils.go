@@ -63,10 +63,12 @@ func insertStringLiterals(selector string, prependWildcard bool, appendWildcard
 	return sql
 }
 
+// escapeLiterals doubles every occurrence of the given quote characters,
+// which is how Postgres escapes them inside quoted literals and identifiers
 func escapeLiterals(selector string, args ...string) string {
-	out := ""
+	out := selector
 	for _, arg := range args {
-		out = strings.ReplaceAll(selector, arg, "")
+		out = strings.ReplaceAll(out, arg, arg+arg)
 	}
 	return out
 }
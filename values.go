@@ -0,0 +1,257 @@
+package strata
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayout is the layout in which time values are written inline
+const timestampLayout = "2006-01-02 15:04:05.999999999Z07:00"
+
+// encodedValue is a Go value in the forms in which it can be written to a
+// statement: arg is the value that is bound to a placeholder, literal is its
+// inline SQL representation, and cast is the Postgres type that a
+// placeholder is cast to when the bound value does not carry the type itself
+type encodedValue struct {
+	arg     interface{}
+	literal string
+	cast    string
+}
+
+// timeType is the type of time values, which named types of time.Time are
+// converted to
+var timeType = reflect.TypeOf(time.Time{})
+
+// encodeValue returns the encoded forms of the value. Values implementing
+// driver.Valuer are encoded by the value they return. Values of named types
+// are encoded by their kind (i.e. a type Zoning string as a string, or a
+// type UUID [16]byte as a uuid), and pointers by the value that they point
+// to, nil pointers being NULL. An error naming the Go type is returned for
+// values that can not be written to a statement
+func encodeValue(value interface{}) (encodedValue, error) {
+	if value == nil {
+		return encodedValue{arg: nil, literal: "NULL"}, nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return encodedValue{arg: nil, literal: "NULL"}, nil
+	}
+
+	switch v := value.(type) {
+	case driver.Valuer:
+		dv, err := v.Value()
+		if err != nil {
+			return encodedValue{}, fmt.Errorf("Could not get the value of %T: %v", value, err)
+		}
+		if _, ok := dv.(driver.Valuer); ok {
+			return encodedValue{}, fmt.Errorf("The value of %T is itself a driver.Valuer", value)
+		}
+		return encodeValue(dv)
+	case time.Time:
+		return encodedValue{arg: v, literal: insertSingleQuotes(v.Format(timestampLayout)) + "::timestamptz"}, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		return encodeValue(rv.Elem().Interface())
+	case reflect.String:
+		return encodedValue{arg: rv.String(), literal: insertSingleQuotes(rv.String())}, nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return encodedValue{arg: true, literal: "TRUE"}, nil
+		}
+		return encodedValue{arg: false, literal: "FALSE"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return encodedValue{arg: rv.Int(), literal: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		text := strconv.FormatUint(rv.Uint(), 10)
+		if rv.Uint() > math.MaxInt64 {
+			// Drivers only bind integers that fit an int64, so larger
+			// values are bound as their decimal text instead
+			return encodedValue{arg: text, literal: text, cast: "numeric"}, nil
+		}
+		return encodedValue{arg: rv.Uint(), literal: text}, nil
+	case reflect.Float32:
+		return encodedValue{arg: float32(rv.Float()), literal: floatSQL(rv.Float(), 32)}, nil
+	case reflect.Float64:
+		return encodedValue{arg: rv.Float(), literal: floatSQL(rv.Float(), 64)}, nil
+	case reflect.Struct:
+		if rv.Type().ConvertibleTo(timeType) {
+			return encodeValue(rv.Convert(timeType).Interface())
+		}
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && rv.Len() == 16 {
+			var u [16]byte
+			reflect.Copy(reflect.ValueOf(&u).Elem(), rv)
+			uuid := uuidString(u)
+			return encodedValue{arg: uuid, literal: insertSingleQuotes(uuid) + "::uuid", cast: "uuid"}, nil
+		}
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := rv.Bytes()
+			return encodedValue{arg: b, literal: insertSingleQuotes(`\x`+hex.EncodeToString(b)) + "::bytea"}, nil
+		}
+		return encodeArray(rv)
+	}
+	return encodedValue{}, fmt.Errorf("Unsupported value of Go type %T", value)
+}
+
+// isNullValue reports whether the value is written to a statement as NULL,
+// such as a nil pointer or a driver.Valuer without a value. An untyped nil is
+// not considered to be a value
+func isNullValue(value interface{}) bool {
+	switch value.(type) {
+	case nil, *TableField, Raw, *Query, jsonPath, Expression:
+		return false
+	}
+	enc, err := encodeValue(value)
+	return err == nil && enc.arg == nil
+}
+
+// floatSQL returns the inline SQL representation of a float. The special
+// values are only understood by Postgres as strings
+func floatSQL(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "'NaN'::float8"
+	case math.IsInf(f, 1):
+		return "'Infinity'::float8"
+	case math.IsInf(f, -1):
+		return "'-Infinity'::float8"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, bitSize)
+	}
+}
+
+// uuidString returns the canonical hyphenated form of a UUID
+func uuidString(u [16]byte) string {
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// arrayElementType returns the Postgres type of the elements of an array
+// that is encoded from a slice of the given element kind
+func arrayElementType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "text"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "smallint"
+	case reflect.Int32, reflect.Uint16:
+		return "integer"
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "bigint"
+	case reflect.Uint, reflect.Uint64:
+		return "numeric"
+	case reflect.Float32:
+		return "real"
+	case reflect.Float64:
+		return "double precision"
+	default:
+		return ""
+	}
+}
+
+// arrayElementText returns the representation of an element within the text
+// representation of an array
+func arrayElementText(arg interface{}) string {
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	case float32:
+		return floatText(float64(v), 32)
+	case float64:
+		return floatText(v, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// floatText returns the text representation of a float that Postgres reads
+func floatText(f float64, bitSize int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, bitSize)
+	}
+}
+
+// encodeArray encodes a slice of strings, booleans or numbers as a Postgres
+// array. The array is bound in its text representation (i.e. {a,b}), which
+// every driver understands, and is therefore cast to its type. The element
+// type of a slice of interface{} is inferred from its elements - when they
+// are of different types (or the slice holds no values at all), the array
+// is left untyped, for Postgres to infer from the comparison
+func encodeArray(rv reflect.Value) (encodedValue, error) {
+	elem := rv.Type().Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	var (
+		dynamic     = elem.Kind() == reflect.Interface
+		elementType = arrayElementType(elem.Kind())
+	)
+	if elementType == "" && !dynamic {
+		return encodedValue{}, fmt.Errorf("Unsupported value of Go type %v", rv.Type())
+	}
+
+	var (
+		literals = make([]string, rv.Len())
+		texts    = make([]string, rv.Len())
+		types    = map[string]bool{}
+	)
+	for i := range literals {
+		element := rv.Index(i).Interface()
+		enc, err := encodeValue(element)
+		if err != nil {
+			return encodedValue{}, err
+		}
+		literals[i] = enc.literal
+		texts[i] = arrayElementText(enc.arg)
+
+		if dynamic && enc.arg != nil {
+			_type := arrayElementType(reflect.ValueOf(enc.arg).Kind())
+			if _type == "" {
+				return encodedValue{}, fmt.Errorf("Unsupported array element of Go type %T", element)
+			}
+			types[_type] = true
+		}
+	}
+	if dynamic {
+		elementType = ""
+		if len(types) == 1 {
+			for _type := range types {
+				elementType = _type
+			}
+		}
+	}
+
+	text := "{" + delimit(",", texts...) + "}"
+	if elementType == "" {
+		return encodedValue{arg: text, literal: insertSingleQuotes(text)}, nil
+	}
+	cast := elementType + "[]"
+	return encodedValue{
+		arg:     text,
+		literal: "ARRAY[" + delimit(", ", literals...) + "]::" + cast,
+		cast:    cast,
+	}, nil
+}
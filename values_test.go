@@ -0,0 +1,138 @@
+package strata
+
+import (
+	"math"
+	"testing"
+)
+
+type zoning string
+
+type uuid [16]byte
+
+func TestEncodeValueByKind(t *testing.T) {
+	var (
+		name     = "north"
+		nilName  *string
+		zone     = zoning("R1")
+		area     = 12.5
+		township = uuid{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	)
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+		arg   interface{}
+	}{
+		{name: "named string", value: zone, want: "'R1'", arg: "R1"},
+		{name: "pointer to string", value: &name, want: "'north'", arg: "north"},
+		{name: "nil pointer", value: nilName, want: "NULL", arg: nil},
+		{name: "pointer to float", value: &area, want: "12.5", arg: 12.5},
+		{name: "named uuid", value: township, want: "'123e4567-e89b-12d3-a456-426614174000'::uuid", arg: "123e4567-e89b-12d3-a456-426614174000"},
+		{name: "slice of named strings", value: []zoning{"R1", "R2"}, want: "ARRAY['R1', 'R2']::text[]", arg: `{"R1","R2"}`},
+		{name: "unsigned", value: uint32(7), want: "7", arg: uint64(7)},
+		{name: "unsigned above int64", value: uint64(math.MaxUint64), want: "18446744073709551615", arg: "18446744073709551615"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := encodeValue(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if enc.literal != tt.want {
+				t.Errorf("got literal %v, want %v", enc.literal, tt.want)
+			}
+			if enc.arg != tt.arg {
+				t.Errorf("got arg %#v, want %#v", enc.arg, tt.arg)
+			}
+		})
+	}
+}
+
+func TestQuantifiedComparisonCasts(t *testing.T) {
+	path := LTreeField("path")
+	id := NumberField("_id")
+	tests := []struct {
+		name  string
+		where Where
+		want  string
+		arg   interface{}
+	}{
+		{
+			name:  "ltree paths",
+			where: Where{LHSField: &path, RHSField: []string{"za.wc", "za.gp"}, ComparisonType: EqualAny},
+			want:  `"path" = ANY ($1::ltree[])`,
+			arg:   `{"za.wc","za.gp"}`,
+		},
+		{
+			name:  "interface{} elements of one type",
+			where: Where{LHSField: &id, RHSField: []interface{}{1, 2}, ComparisonType: EqualAny},
+			want:  `"_id" = ANY ($1::bigint[])`,
+			arg:   `{1,2}`,
+		},
+		{
+			name:  "interface{} elements of mixed types",
+			where: Where{LHSField: &id, RHSField: []interface{}{1, "2", nil}, ComparisonType: NotEqualAll},
+			want:  `"_id" <> ALL ($1)`,
+			arg:   `{1,"2",NULL}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := tt.where.SQLWithArgs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if len(args) != 1 || args[0] != tt.arg {
+				t.Errorf("got args %#v, want %#v", args, tt.arg)
+			}
+		})
+	}
+}
+
+func TestBoundValues(t *testing.T) {
+	var (
+		id      = NumberField("_id")
+		name    = StringField("name")
+		nilName *string
+	)
+	tests := []struct {
+		name  string
+		where Where
+		want  string
+		args  int
+	}{
+		{
+			name:  "unsigned above int64",
+			where: Where{LHSField: &id, RHSField: uint64(math.MaxUint64), ComparisonType: Equal},
+			want:  `"_id" = $1::numeric`,
+			args:  1,
+		},
+		{
+			name:  "equal to a nil pointer",
+			where: Where{LHSField: &name, RHSField: nilName, ComparisonType: Equal},
+			want:  `"name" IS NULL`,
+		},
+		{
+			name:  "not equal to a nil pointer",
+			where: Where{LHSField: &name, RHSField: nilName, ComparisonType: NotEqual},
+			want:  `"name" IS NOT NULL`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := tt.where.SQLWithArgs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if len(args) != tt.args {
+				t.Errorf("got args %#v, want %v of them", args, tt.args)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package strata
 
 import (
 	"fmt"
+	"reflect"
 )
 
 // Where is the abstraction of a where condition
@@ -17,46 +18,223 @@ type Wheres struct {
 	IsInclusive bool
 }
 
-func (w *Where) rightFieldSQL() string {
-	if w.RHSField == nil {
-		return ""
+// Range is the RHS of the Between and NotBetween comparisons. Both bounds
+// are inclusive
+type Range struct {
+	From interface{}
+	To   interface{}
+}
+
+func (w *Where) rightFieldSQL(b *builder) (string, error) {
+	if w.RHSField == nil || !w.ComparisonType.NeedsRHS() {
+		return "", nil
+	}
+	switch {
+	case w.ComparisonType == TextSearchMatches:
+		return textSearchSQL(b, w.RHSField)
+	case w.ComparisonType.IsJSON():
+		return w.jsonSQL(b)
+	case w.ComparisonType.IsQuantified():
+		return w.quantifiedSQL(b)
+	case w.ComparisonType.NeedsRange():
+		return w.rangeSQL(b)
+	case w.ComparisonType.NeedsList():
+		return w.listSQL(b)
 	}
 	switch rhs := w.RHSField.(type) {
 	case *TableField:
-		return rhs.WhereClauseSQL()
+		sql, err := rhs.selectorSQL(b)
+		if err != nil || w.ComparisonType.IsExact() {
+			return sql, err
+		}
+		return "'%' || " + sql + " || '%'", nil
 	case string:
-		islike := !w.ComparisonType.IsExact()
-		return insertStringLiterals(rhs, islike, islike)
-	case int:
-		return fmt.Sprintf("%v", rhs)
-	case int64:
-		return fmt.Sprintf("%v", rhs)
+		if !w.ComparisonType.IsExact() {
+			rhs = "%" + rhs + "%"
+		}
+		return w.operandSQL(b, rhs)
+	default:
+		sql, err := w.operandSQL(b, rhs)
+		if err != nil {
+			return "", fmt.Errorf("Right hand side of %v comparison: %v", w.ComparisonType.SQL(), err)
+		}
+		return sql, nil
+	}
+}
+
+// operandSQL returns the SQL of a value on the RHS of the comparison, cast to
+// the type that the comparison needs - see ComparisonType.valueCast
+func (w *Where) operandSQL(b *builder, value interface{}) (string, error) {
+	if cast := w.ComparisonType.valueCast(w.LHSField); cast != "" {
+		return b.castOperandSQL(value, cast)
+	}
+	return b.operandSQL(value)
+}
+
+// quantifiedSQL returns the parenthesised array (or subquery) whose elements
+// the LHS is compared to
+func (w *Where) quantifiedSQL(b *builder) (string, error) {
+	if q, ok := w.RHSField.(*Query); ok {
+		return q.subquerySQL(b)
+	}
+	sql, err := w.operandSQL(b, w.RHSField)
+	if err != nil {
+		return "", fmt.Errorf("Right hand side of %v comparison: %v", w.ComparisonType.SQL(), err)
+	}
+	return "(" + sql + ")", nil
+}
+
+// rangeSQL returns the bounds of the range that the LHS is compared to,
+// which are given as a Range or as a slice of two values
+func (w *Where) rangeSQL(b *builder) (string, error) {
+	var bounds Range
+	switch rhs := w.RHSField.(type) {
+	case Range:
+		bounds = rhs
+	case *Range:
+		bounds = *rhs
 	default:
-		return ""
+		v := reflect.ValueOf(rhs)
+		if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Len() != 2 {
+			return "", fmt.Errorf("Comparison type %v needs a Range or two values, got %T", w.ComparisonType.SQL(), rhs)
+		}
+		bounds = Range{From: v.Index(0).Interface(), To: v.Index(1).Interface()}
 	}
+
+	from, err := w.operandSQL(b, bounds.From)
+	if err != nil {
+		return "", err
+	}
+	to, err := w.operandSQL(b, bounds.To)
+	if err != nil {
+		return "", err
+	}
+	return delimitSpace(from, "AND", to), nil
+}
+
+// listSQL returns the parenthesised list of values that the LHS is compared
+// to, which are given as a slice or as a subquery
+func (w *Where) listSQL(b *builder) (string, error) {
+	if q, ok := w.RHSField.(*Query); ok {
+		return q.subquerySQL(b)
+	}
+
+	v := reflect.ValueOf(w.RHSField)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("Comparison type %v needs a list of values or a subquery, got %T", w.ComparisonType.SQL(), w.RHSField)
+	}
+	if v.Len() == 0 {
+		return "", fmt.Errorf("Comparison type %v needs at least one value", w.ComparisonType.SQL())
+	}
+
+	values := make([]string, v.Len())
+	for i := range values {
+		s, err := w.operandSQL(b, v.Index(i).Interface())
+		if err != nil {
+			return "", fmt.Errorf("Value %v: %v", i, err)
+		}
+		values[i] = s
+	}
+	return "(" + delimit(", ", values...) + ")", nil
 }
 
 // WhereSet is a set of Where objects - they will be marshalled into a
 // set of larger where conditions using the OR keywords
 type WhereSet []Wheres
 
+func (w Where) conditionSQL(b *builder) (string, error) {
+	return w.sql(b)
+}
+
+func (ws Wheres) conditionSQL(b *builder) (string, error) {
+	return ws.sql(b)
+}
+
+func (ws Wheres) operatorSQL(b *builder) (string, string, error) {
+	sql, err := ws.sql(b)
+	if err != nil || len(ws.Wheres) < 2 {
+		return sql, "", err
+	}
+	return sql, ws.unionSQL(), nil
+}
+
+func (ws WhereSet) conditionSQL(b *builder) (string, error) {
+	return ws.sql(b)
+}
+
+func (ws WhereSet) operatorSQL(b *builder) (string, string, error) {
+	return ws.group().operatorSQL(b)
+}
+
+// group returns the WhereSet as the equivalent OR group of its Wheres
+func (ws WhereSet) group() *ConditionGroup {
+	conditions := make([]Condition, 0, len(ws))
+	for _, where := range ws {
+		conditions = append(conditions, where)
+	}
+	return Or(conditions...)
+}
+
 // SQL returns the SQL for the given where condition
 func (w *Where) SQL() (string, error) {
+	return w.sql(inlineBuilder())
+}
+
+// SQLWithArgs returns the SQL for the given where condition with every
+// value replaced by a positional placeholder, along with the values in
+// placeholder order
+func (w *Where) SQLWithArgs() (string, []interface{}, error) {
+	return conditionSQLWithArgs(w)
+}
+
+func (w *Where) sql(b *builder) (string, error) {
 	// Do absolutely nothing if there is no left hand side of the comparison
 	if w.LHSField == nil {
 		return "", fmt.Errorf("No left hand field object provided")
 	}
+	if b.rows {
+		fields := []*TableField{w.LHSField}
+		if rhs, ok := w.RHSField.(*TableField); ok {
+			fields = append(fields, rhs)
+		}
+		for _, field := range fields {
+			if field.IsAggregate() || field.IsWindow() {
+				return "", fmt.Errorf("Field %v is computed over groups of rows and can not be compared in the WHERE clause - use SetHaving instead", field.outputName())
+			}
+		}
+	}
 
-	rhs := w.rightFieldSQL()
+	lhs, err := w.LHSField.selectorSQL(b)
+	if err != nil {
+		return "", err
+	}
+	if w.ComparisonType.IsLocate() {
+		return w.locateSQL(b, lhs)
+	}
+	if w.ComparisonType.IsSpatial() {
+		return w.spatialSQL(b, lhs)
+	}
+	if (w.ComparisonType == Equal || w.ComparisonType == NotEqual) && isNullValue(w.RHSField) {
+		// A comparison to NULL is never true, so a NULL value (i.e. a nil
+		// pointer) is tested for with IS NULL instead
+		if w.ComparisonType == Equal {
+			return delimitSpace(lhs, "IS NULL"), nil
+		}
+		return delimitSpace(lhs, "IS NOT NULL"), nil
+	}
+	rhs, err := w.rightFieldSQL(b)
+	if err != nil {
+		return "", err
+	}
 	if w.ComparisonType.NeedsRHS() && rhs == "" {
 		return "", fmt.Errorf("No right hand field object provided - is necessary for comparison type")
 	}
 
-	return delimitSpace(
-		w.LHSField.WhereClauseSQL(),
-		w.ComparisonType.SQL(),
-		rhs,
-	), nil
+	sql := delimitSpace(lhs, w.ComparisonType.SQL())
+	if rhs != "" {
+		sql = delimitSpace(sql, rhs)
+	}
+	return sql, nil
 }
 
 // Append appends a where condition to the where object
@@ -77,12 +255,22 @@ func (ws *Wheres) ToNativeSlice() []Where {
 
 // SQL returns the SQL representation of the where conditions
 func (ws *Wheres) SQL() (string, error) {
+	return ws.sql(inlineBuilder())
+}
+
+// SQLWithArgs returns the parameterized SQL representation of the where
+// conditions, along with the values in placeholder order
+func (ws *Wheres) SQLWithArgs() (string, []interface{}, error) {
+	return conditionSQLWithArgs(ws)
+}
+
+func (ws *Wheres) sql(b *builder) (string, error) {
 	sql := ""
 	for i, w := range ws.Wheres {
 		if i > 0 {
-			sql += ws.unionSQL()
+			sql += surroundWithSpaces(ws.unionSQL())
 		}
-		s, err := w.SQL()
+		s, err := w.sql(b)
 		if err != nil {
 			return "", err
 		}
@@ -112,21 +300,18 @@ func (ws *WhereSet) append(whereSet ...Wheres) {
 }
 
 // SQL return an SQL representation of the WhereSet. It assumes that
-// the Wheresets will be included with "OR" conditions
+// the Wheresets will be included with "OR" conditions. Empty Wheres are
+// skipped and those joined by AND are parenthesised
 func (ws *WhereSet) SQL(whereSet ...Wheres) (string, error) {
-	sql := ""
-	for i, where := range *ws {
-		if where.Wheres == nil || len(where.Wheres) == 0 {
-			break
-		}
-		if i > 0 {
-			sql += " OR "
-		}
-		_w, err := where.SQL()
-		if err != nil {
-			return "", err
-		}
-		sql += _w
-	}
-	return sql, nil
+	return ws.sql(inlineBuilder())
+}
+
+// SQLWithArgs returns the parameterized SQL representation of the
+// WhereSet, along with the values in placeholder order
+func (ws *WhereSet) SQLWithArgs() (string, []interface{}, error) {
+	return conditionSQLWithArgs(ws)
+}
+
+func (ws *WhereSet) sql(b *builder) (string, error) {
+	return ws.group().conditionSQL(b)
 }
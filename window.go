@@ -0,0 +1,324 @@
+package strata
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// WindowFunction is the enumerated window function that is applied to a
+// field. Aggregates (such as Sum) are computed over a window by giving the
+// field an Over window instead
+type WindowFunction int
+
+const (
+	// NoWindowFunction leaves the field as is
+	NoWindowFunction WindowFunction = iota
+	// RowNumber numbers the rows of the partition from 1
+	RowNumber
+	// Rank ranks the rows of the partition, with gaps for ties
+	Rank
+	// DenseRank ranks the rows of the partition, without gaps for ties
+	DenseRank
+	// PercentRank is the relative rank of the rows of the partition
+	PercentRank
+	// CumeDist is the cumulative distribution of the rows of the partition
+	CumeDist
+	// NTile divides the partition into the number of buckets given by the
+	// first of the WindowArgs, and numbers the rows by their bucket
+	NTile
+	// Lag returns the value of the field in the row preceding the current
+	// row. The WindowArgs optionally give the offset and the default value
+	Lag
+	// Lead returns the value of the field in the row following the current
+	// row. The WindowArgs optionally give the offset and the default value
+	Lead
+	// FirstValue returns the value of the field in the first row of the frame
+	FirstValue
+	// LastValue returns the value of the field in the last row of the frame
+	LastValue
+	// NthValue returns the value of the field in the row of the frame given
+	// by the first of the WindowArgs
+	NthValue
+)
+
+// SQL returns the name of the window function
+func (wf *WindowFunction) SQL() string {
+	if wf == nil {
+		return ""
+	}
+	switch *wf {
+	case RowNumber:
+		return "ROW_NUMBER"
+	case Rank:
+		return "RANK"
+	case DenseRank:
+		return "DENSE_RANK"
+	case PercentRank:
+		return "PERCENT_RANK"
+	case CumeDist:
+		return "CUME_DIST"
+	case NTile:
+		return "NTILE"
+	case Lag:
+		return "LAG"
+	case Lead:
+		return "LEAD"
+	case FirstValue:
+		return "FIRST_VALUE"
+	case LastValue:
+		return "LAST_VALUE"
+	case NthValue:
+		return "NTH_VALUE"
+	case NoWindowFunction:
+		fallthrough
+	default:
+		return ""
+	}
+}
+
+// takesField returns whether the window function is applied to the value of
+// the field, rather than only to the position of the row
+func (wf WindowFunction) takesField() bool {
+	switch wf {
+	case Lag, Lead, FirstValue, LastValue, NthValue:
+		return true
+	default:
+		return false
+	}
+}
+
+// FrameMode is the unit in which the bounds of a window frame are given
+type FrameMode int
+
+const (
+	// FrameRange bounds the frame by the values of the ORDER BY field
+	FrameRange FrameMode = iota
+	// FrameRows bounds the frame by a number of rows
+	FrameRows
+	// FrameGroups bounds the frame by a number of peer groups
+	FrameGroups
+)
+
+// SQL returns the SQL representation of the frame mode
+func (fm *FrameMode) SQL() string {
+	if fm == nil {
+		return ""
+	}
+	switch *fm {
+	case FrameRows:
+		return "ROWS"
+	case FrameGroups:
+		return "GROUPS"
+	default:
+		return "RANGE"
+	}
+}
+
+// FrameBoundType is the enumerated bound of a window frame
+type FrameBoundType int
+
+const (
+	// UnboundedPreceding bounds the frame at the first row of the partition
+	UnboundedPreceding FrameBoundType = iota
+	// Preceding bounds the frame at Offset before the current row
+	Preceding
+	// CurrentRow bounds the frame at the current row
+	CurrentRow
+	// Following bounds the frame at Offset after the current row
+	Following
+	// UnboundedFollowing bounds the frame at the last row of the partition
+	UnboundedFollowing
+)
+
+// FrameBound is a bound of a window frame
+type FrameBound struct {
+	Type   FrameBoundType
+	Offset int
+}
+
+// SQL returns the SQL representation of the frame bound
+func (fb *FrameBound) SQL() string {
+	if fb == nil {
+		return ""
+	}
+	switch fb.Type {
+	case UnboundedPreceding:
+		return "UNBOUNDED PRECEDING"
+	case Preceding:
+		return strconv.Itoa(fb.Offset) + " PRECEDING"
+	case Following:
+		return strconv.Itoa(fb.Offset) + " FOLLOWING"
+	case UnboundedFollowing:
+		return "UNBOUNDED FOLLOWING"
+	case CurrentRow:
+		fallthrough
+	default:
+		return "CURRENT ROW"
+	}
+}
+
+// WindowFrame is the frame clause of a window, i.e. the set of rows of the
+// partition that the function is computed over. The frame ends at the
+// current row when End is undefined
+type WindowFrame struct {
+	Mode  FrameMode
+	Start FrameBound
+	End   *FrameBound
+}
+
+// SQL returns the SQL representation of the window frame
+func (wf *WindowFrame) SQL() string {
+	if wf == nil {
+		return ""
+	}
+	if wf.End == nil {
+		return delimitSpace(wf.Mode.SQL(), wf.Start.SQL())
+	}
+	return delimitSpace(wf.Mode.SQL(), "BETWEEN", wf.Start.SQL(), "AND", wf.End.SQL())
+}
+
+// Window is the specification of the window that a window function (or an
+// aggregate) is computed over. Name refers to a window defined on the query
+// using AddWindow, which the window extends with its own ordering and frame
+type Window struct {
+	Name        string
+	PartitionBy []*TableField
+	OrderBy     OrderBys
+	Frame       *WindowFrame
+}
+
+// isReference returns whether the window refers to a named window as is
+func (w *Window) isReference() bool {
+	return w.Name != "" && len(w.PartitionBy) == 0 && len(w.OrderBy) == 0 && w.Frame == nil
+}
+
+// specificationSQL returns the window specification, without parentheses
+func (w *Window) specificationSQL(b *builder) (string, error) {
+	var parts []string
+	if w.Name != "" {
+		parts = append(parts, insertDoubleQuotes(w.Name))
+	}
+
+	if len(w.PartitionBy) > 0 {
+		partitions := make([]string, len(w.PartitionBy))
+		for i, field := range w.PartitionBy {
+			s, err := field.selectorSQL(b)
+			if err != nil {
+				return "", err
+			}
+			partitions[i] = s
+		}
+		parts = append(parts, "PARTITION BY "+delimit(", ", partitions...))
+	}
+
+	if len(w.OrderBy) > 0 {
+		orderBy, err := w.OrderBy.sql(b, false)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "ORDER BY "+orderBy)
+	}
+
+	if w.Frame != nil {
+		parts = append(parts, w.Frame.SQL())
+	}
+	return delimitSpace(parts...), nil
+}
+
+// overSQL returns the OVER clause of the window
+func (w *Window) overSQL(b *builder) (string, error) {
+	if w.isReference() {
+		return "OVER " + insertDoubleQuotes(w.Name), nil
+	}
+	spec, err := w.specificationSQL(b)
+	if err != nil {
+		return "", err
+	}
+	return "OVER (" + spec + ")", nil
+}
+
+// windowSQL applies the window function of the field (or its aggregate) to
+// the selector, followed by the OVER clause of its window
+func (tf *TableField) windowSQL(b *builder, selector string) (string, error) {
+	if tf.WindowFunction != NoWindowFunction {
+		args := []string{}
+		if tf.WindowFunction.takesField() {
+			args = append(args, selector)
+		}
+		for i, arg := range tf.WindowArgs {
+			s, err := b.operandSQL(arg)
+			if err != nil {
+				return "", fmt.Errorf("Window function argument %v: %v", i, err)
+			}
+			args = append(args, s)
+		}
+		selector = tf.WindowFunction.SQL() + "(" + delimit(", ", args...) + ")"
+	} else {
+		selector = tf.aggregateSQL(selector)
+	}
+
+	if tf.Over == nil {
+		if tf.WindowFunction != NoWindowFunction {
+			return "", fmt.Errorf("Window function %v has no window", tf.WindowFunction.SQL())
+		}
+		return selector, nil
+	}
+
+	over, err := tf.Over.overSQL(b)
+	if err != nil {
+		return "", err
+	}
+	return delimitSpace(selector, over), nil
+}
+
+// IsWindow returns whether the field is computed over a window
+func (tf *TableField) IsWindow() bool {
+	return tf.Over != nil
+}
+
+// WindowField returns a field that applies the window function over the
+// window to the column with the given name. The name is only used by the
+// window functions that take the value of a field, such as Lag
+func WindowField(name string, function WindowFunction, over *Window, args ...interface{}) TableField {
+	return TableField{
+		Name:           name,
+		WindowFunction: function,
+		WindowArgs:     args,
+		Over:           over,
+	}
+}
+
+// OverWindow returns a copy of the field that is computed over the window,
+// which turns aggregated fields into window aggregates
+func OverWindow(tf TableField, over *Window) TableField {
+	tf.Over = over
+	return tf
+}
+
+// namedWindow is a window defined in the WINDOW clause of a query
+type namedWindow struct {
+	name   string
+	window *Window
+}
+
+// AddWindow defines a named window in the WINDOW clause of the query, which
+// windows of fields can refer to by name
+func (q *Query) AddWindow(name string, window *Window) {
+	q.windows = append(q.windows, namedWindow{name: name, window: window})
+}
+
+// windowsSQL returns the WINDOW clause of the query, without the keyword
+func (q *Query) windowsSQL(b *builder) (string, error) {
+	sql := ""
+	for i, nw := range q.windows {
+		if i > 0 {
+			sql += ", "
+		}
+		spec, err := nw.window.specificationSQL(b)
+		if err != nil {
+			return "", fmt.Errorf("Window %v: %v", nw.name, err)
+		}
+		sql += insertDoubleQuotes(nw.name) + " AS (" + spec + ")"
+	}
+	return sql, nil
+}